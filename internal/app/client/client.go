@@ -6,16 +6,23 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/balancer"
 	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
-	"github.com/kirill-a-belov/test_task_framework/internal/pkg/network"
-	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/discovery"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/load"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/payload"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/pool"
+	"github.com/kirill-a-belov/test_task_framework/pkg/backoff"
+	"github.com/kirill-a-belov/test_task_framework/pkg/breaker"
 	"github.com/kirill-a-belov/test_task_framework/pkg/context_helper"
 	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
-	"github.com/kirill-a-belov/test_task_framework/pkg/rand"
+	"github.com/kirill-a-belov/test_task_framework/pkg/metrics"
 	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
 )
 
@@ -23,15 +30,39 @@ func New(ctx context.Context, config *config.Config) *Client {
 	_, span := tracer.Start(ctx, "internal.app.client.New")
 	defer span.End()
 
-	return &Client{
+	c := &Client{
 		config:   config,
 		stopChan: make(chan struct{}),
 		logger:   logger.New("client"),
 
-		dialler: func() (net.Conn, error) {
-			return net.Dial(protocol.NetworkType, config.Address)
+		retryPolicy: config.RetryPolicy(),
+
+		breakerStateGauge:      metrics.NewGauge("client.breaker.state"),
+		breakerOpenedCounter:   metrics.NewCounter("client.breaker.opened"),
+		breakerRejectedCounter: metrics.NewCounter("client.breaker.rejected"),
+
+		balancer: balancer.New(ctx, config),
+
+		operationList: map[string]Operation{
+			OperationSum: sum,
 		},
+		expectationList: map[string]Expectation{
+			OperationSum: expectSum,
+		},
+		verifiedCounter: metrics.NewCounter("client.verify.checked"),
+		mismatchCounter: metrics.NewCounter("client.verify.mismatch"),
+
+		metricsExporter: metrics.NewExporter(config.MetricsInterval),
 	}
+	c.discovery = discovery.New(ctx, config, c.balancer.Update)
+	c.payload = payload.New(ctx, config)
+	c.dialler = c.balancer.Dial
+	c.pool = pool.New(ctx, config, func(ctx context.Context) (net.Conn, error) {
+		return c.dialler(ctx)
+	})
+	c.breaker = breaker.New(config.BreakerPolicy(), c.breakerStateChanged)
+
+	return c
 }
 
 type Client struct {
@@ -39,7 +70,26 @@ type Client struct {
 	stopChan chan struct{}
 	logger   logger.Logger
 
-	dialler func() (net.Conn, error)
+	balancer  *balancer.Balancer
+	discovery *discovery.Discovery
+	dialler   func(context.Context) (net.Conn, error)
+	pool      *pool.Pool
+	payload   payload.Generator
+
+	operationMu     sync.RWMutex
+	operationList   map[string]Operation
+	expectationList map[string]Expectation
+	retryPolicy     backoff.Policy
+	breaker         *breaker.Breaker
+
+	verifiedCounter *metrics.Counter
+	mismatchCounter *metrics.Counter
+
+	breakerStateGauge      *metrics.Gauge
+	breakerOpenedCounter   *metrics.Counter
+	breakerRejectedCounter *metrics.Counter
+
+	metricsExporter *metrics.Exporter
 }
 
 func (c *Client) Start(ctx context.Context) error {
@@ -48,15 +98,22 @@ func (c *Client) Start(ctx context.Context) error {
 
 	c.logger.Info(fmt.Sprintf("config: %+v", *c.config))
 
-	go c.processor(ctx, handle)
+	if err := c.discovery.Start(ctx); err != nil {
+		return errors.Wrap(err, "starting endpoint discovery")
+	}
+	c.balancer.Start(ctx)
+	c.pool.Start(ctx)
+	c.metricsExporter.Start(ctx)
+	go c.processor(ctx, c.handle)
 
 	return nil
 }
 
-func (c *Client) processor(ctx context.Context, handler func(io.ReadWriter) error) {
+func (c *Client) processor(ctx context.Context, handler func(context.Context, io.ReadWriter) error) {
 	_, span := tracer.Start(ctx, "internal.app.client.Client.processor")
 	defer span.End()
 
+	dialFailedCnt := 0
 	for {
 		select {
 		case <-c.stopChan:
@@ -64,19 +121,34 @@ func (c *Client) processor(ctx context.Context, handler func(io.ReadWriter) erro
 
 			return
 		default:
-			conn, err := c.dialler()
+			conn, err := c.acquire(ctx)
+			if errors.Is(err, breaker.ErrOpen) {
+				c.wait(c.config.Delay)
+
+				continue
+			}
 			if err != nil {
-				c.logger.Error(err, "connection dialing")
+				dialFailedCnt++
+				c.logger.Error(err, "connection dialing", "attempt", dialFailedCnt)
+				c.wait(c.retryPolicy.Delay(dialFailedCnt))
 
 				continue
 			}
+			dialFailedCnt = 0
 
 			go func() {
-				defer func(conn net.Conn) {
-					_ = conn.Close()
-				}(conn)
-				if err := context_helper.RunWithTimeout(c.config.ConnTTL, func() error {
-					return handler(conn)
+				if err := c.retryPolicy.Retry(ctx, func() error {
+					if conn == nil {
+						var err error
+						if conn, err = c.acquire(ctx); err != nil {
+							return errors.Wrap(err, "connection dialing")
+						}
+					}
+					defer func() {
+						conn = nil
+					}()
+
+					return c.exchange(ctx, conn, handler)
 				}); err != nil {
 					c.logger.Error(err, "connection handling")
 				}
@@ -87,47 +159,158 @@ func (c *Client) processor(ctx context.Context, handler func(io.ReadWriter) erro
 	}
 }
 
+// acquire takes connection from pool unless circuit breaker fails fast, connecting stops when context is done,
+// pooled connections of ejected or removed endpoints are discarded
+func (c *Client) acquire(ctx context.Context) (*pool.Conn, error) {
+	if err := c.breaker.Allow(); err != nil {
+		c.breakerRejectedCounter.Inc()
+
+		return nil, err
+	}
+
+	for {
+		conn, err := c.pool.Get(ctx)
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("%w: %w", ErrDeadlineExceeded, err)
+			}
+			c.breaker.Done(err)
+
+			return nil, err
+		}
+		if e := endpoint(conn); e != nil && (e.Removed() || !e.Healthy()) {
+			c.pool.Discard(conn)
+
+			continue
+		}
+
+		return conn, nil
+	}
+}
+
+// exchange runs handler within context and connection TTL, handler receives context carrying the deadline,
+// returns healthy connection to pool
+func (c *Client) exchange(ctx context.Context, conn *pool.Conn, handler func(context.Context, io.ReadWriter) error) error {
+	if c.config.ConnTTL > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.ConnTTL)
+		defer cancel()
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	e := endpoint(conn)
+	if e != nil {
+		e.Begin()
+	}
+	err := context_helper.RunWithContext(ctx, func() error {
+		return handler(ctx, conn)
+	})
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		err = fmt.Errorf("%w: %w", ErrDeadlineExceeded, err)
+	}
+	if e != nil {
+		e.End(err)
+	}
+	c.breaker.Done(err)
+	if err != nil {
+		c.pool.Discard(conn)
+
+		return err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	c.pool.Put(conn)
+
+	return nil
+}
+
+// endpoint returns balanced endpoint of connection if any
+func endpoint(conn *pool.Conn) *balancer.Endpoint {
+	if bc, ok := conn.Conn.(*balancer.Conn); ok {
+		return bc.Endpoint
+	}
+
+	return nil
+}
+
+func (c *Client) breakerStateChanged(from, to breaker.State) {
+	c.logger.Info("circuit breaker state changed", "from", from, "to", to)
+	c.breakerStateGauge.Set(int64(to))
+	if to == breaker.StateOpen {
+		c.breakerOpenedCounter.Inc()
+	}
+}
+
+// wait sleeps for delay or until client is stopped
+func (c *Client) wait(delay time.Duration) {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-c.stopChan:
+	case <-timer.C:
+	}
+}
+
 func (c *Client) Stop(ctx context.Context) {
 	_, span := tracer.Start(ctx, "internal.app.client.Client.Stop")
 	defer span.End()
 
 	close(c.stopChan)
+	c.discovery.Stop(ctx)
+	c.pool.Stop(ctx)
+	c.balancer.Stop(ctx)
+	c.metricsExporter.Stop(ctx)
 }
 
-func handle(conn io.ReadWriter) error {
-	ctx, span := tracer.Start(context.Background(), "internal.app.client.Client.handle")
+// RunLoad generates load with Sum requests and returns report
+func (c *Client) RunLoad(ctx context.Context) (*load.Report, error) {
+	ctx, span := tracer.Start(ctx, "internal.app.client.Client.RunLoad")
 	defer span.End()
 
-	const (
-		payloadSize     = 3
-		payloadMaxDigit = 1024
-	)
-	payload, err := rand.Rand(payloadSize, payloadMaxDigit)
-	if err != nil {
-		return errors.Wrap(err, "generating payload")
-	}
+	c.logger.Info(fmt.Sprintf("config: %+v", *c.config))
 
-	if err := network.Send(ctx, conn, protocol.Request{
-		Message: protocol.Message{
-			Type: protocol.MessageTypeRequest,
-		},
-		Payload: payload,
-	}); err != nil {
-		return errors.Wrap(err, "sending request")
+	if err := c.discovery.Start(ctx); err != nil {
+		return nil, errors.Wrap(err, "starting endpoint discovery")
 	}
+	c.balancer.Start(ctx)
+	c.pool.Start(ctx)
+	c.metricsExporter.Start(ctx)
+	defer c.Stop(ctx)
+
+	return load.New(ctx, c.config, func(ctx context.Context) error {
+		args, err := c.payload.Next()
+		if err != nil {
+			return errors.Wrap(err, "generating payload")
+		}
+		_, err = c.Sum(ctx, args)
 
-	response, err := network.Receive[protocol.Response](ctx, conn)
+		return err
+	}, ErrorClass).Run(ctx), nil
+}
+
+func (c *Client) handle(ctx context.Context, conn io.ReadWriter) error {
+	ctx, span := tracer.Start(ctx, "internal.app.client.Client.handle")
+	defer span.End()
+
+	args, err := c.payload.Next()
 	if err != nil {
-		return errors.Wrap(err, "receiving server response")
+		return errors.Wrap(err, "generating payload")
 	}
-	if response.Type != protocol.MessageTypeResponse {
-		return errors.Errorf("server response: received wrong message (%v)", response)
+
+	result, err := sum(ctx, conn, args)
+	if err != nil {
+		return err
 	}
 
 	logger.New("client.handle").Info("handling connection",
-		"sent", payload,
-		"received", response.Payload,
+		"sent", args,
+		"received", result,
 	)
 
+	// mismatch is reported by verify, connection stays healthy
+	_ = c.verify(OperationSum, args, result)
+
 	return nil
 }
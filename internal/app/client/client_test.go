@@ -6,6 +6,8 @@ import (
 	"encoding/gob"
 	"io"
 	"net"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -16,7 +18,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/network"
 	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/pkg/breaker"
 	"github.com/kirill-a-belov/test_task_framework/pkg/test_helper"
 )
 
@@ -53,12 +57,12 @@ func TestClient_Start(t *testing.T) {
 func TestClient_processor(t *testing.T) {
 	testCaseList := []struct {
 		name string
-		args func() (*Client, func(io.ReadWriter) error)
+		args func() (*Client, func(context.Context, io.ReadWriter) error)
 	}{
 		{
 			name: "Regular stop",
-			args: func() (*Client, func(io.ReadWriter) error) {
-				f := func(writer io.ReadWriter) error {
+			args: func() (*Client, func(context.Context, io.ReadWriter) error) {
+				f := func(_ context.Context, writer io.ReadWriter) error {
 					return nil
 				}
 
@@ -79,8 +83,8 @@ func TestClient_processor(t *testing.T) {
 		},
 		{
 			name: "Handling func error",
-			args: func() (*Client, func(io.ReadWriter) error) {
-				f := func(writer io.ReadWriter) error {
+			args: func() (*Client, func(context.Context, io.ReadWriter) error) {
+				f := func(_ context.Context, writer io.ReadWriter) error {
 					return errors.New("example error")
 				}
 
@@ -102,8 +106,8 @@ func TestClient_processor(t *testing.T) {
 		},
 		{
 			name: "Dialing error",
-			args: func() (*Client, func(io.ReadWriter) error) {
-				f := func(writer io.ReadWriter) error {
+			args: func() (*Client, func(context.Context, io.ReadWriter) error) {
+				f := func(_ context.Context, writer io.ReadWriter) error {
 					return nil
 				}
 
@@ -145,16 +149,184 @@ func TestClient_processor(t *testing.T) {
 	}
 }
 
+func TestClient_processor_deadline(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	deadlineChan := make(chan time.Time, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		ctx := context.Background()
+		request, err := network.Receive[protocol.Request](ctx, conn)
+		if err != nil {
+			return
+		}
+		deadlineChan <- request.Deadline
+		_ = network.Send(ctx, conn, protocol.Response{
+			Message: protocol.Message{
+				Type: protocol.MessageTypeResponse,
+			},
+		})
+	}()
+
+	loggerMock := &test_helper.LoggerMock{}
+	loggerMock.On("Info", mock.Anything)
+	loggerMock.On("Error", mock.Anything, mock.Anything)
+
+	c := New(context.Background(), &config.Config{
+		Delay:   time.Second,
+		ConnTTL: time.Second,
+	})
+	c.logger = loggerMock
+	c.dialler = func(context.Context) (net.Conn, error) {
+		return net.Dial("tcp", listener.Addr().String())
+	}
+
+	ctx := context.Background()
+	start := time.Now()
+	go c.processor(ctx, c.handle)
+	defer c.Stop(ctx)
+
+	select {
+	case deadline := <-deadlineChan:
+		assert.False(t, deadline.IsZero())
+		assert.WithinDuration(t, start.Add(time.Second), deadline, time.Second)
+	case <-time.After(time.Second):
+		assert.Fail(t, "request was not received")
+	}
+}
+
 type diallerMock struct {
 	mock.Mock
 }
 
-func (dm *diallerMock) mockFunc() (net.Conn, error) {
+func (dm *diallerMock) mockFunc(context.Context) (net.Conn, error) {
 	args := dm.Called()
 
 	return args.Get(0).(net.Conn), args.Error(1)
 }
 
+func TestClient_acquire(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      func() *Client
+		wantError error
+	}{
+		{
+			name: "Success",
+			args: func() *Client {
+				c := New(context.Background(), &config.Config{})
+
+				diallerMock := &diallerMock{}
+				diallerMock.On("mockFunc").Return(&net.TCPConn{}, nil).Once()
+				c.dialler = diallerMock.mockFunc
+
+				return c
+			},
+		},
+		{
+			name: "Circuit breaker open",
+			args: func() *Client {
+				c := New(context.Background(), &config.Config{
+					BreakerConsecutiveFailures: 1,
+					BreakerOpenTimeout:         time.Hour,
+				})
+				c.logger = &test_helper.LoggerMock{}
+				c.logger.(*test_helper.LoggerMock).On("Info", mock.Anything)
+
+				diallerMock := &diallerMock{}
+				diallerMock.On("mockFunc").Return(&net.TCPConn{}, errors.New("example error")).Once()
+				c.dialler = diallerMock.mockFunc
+
+				_, err := c.acquire(context.Background())
+				require.Error(t, err)
+
+				return c
+			},
+			wantError: breaker.ErrOpen,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.args().acquire(context.Background())
+			if tc.wantError != nil {
+				assert.ErrorIs(t, err, tc.wantError)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestClient_acquire_removedEndpoint(t *testing.T) {
+	listen := func() string {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = listener.Close()
+		})
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				t.Cleanup(func() {
+					_ = conn.Close()
+				})
+			}
+		}()
+
+		return listener.Addr().String()
+	}
+	removedAddress, addedAddress := listen(), listen()
+
+	path := filepath.Join(t.TempDir(), "endpoints.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"endpoints": ["`+removedAddress+`"]}`), 0o600))
+
+	ctx := context.Background()
+	c := New(ctx, &config.Config{
+		DiscoveryFile:         path,
+		DiscoveryFileInterval: 10 * time.Millisecond,
+		PoolMaxIdle:           1,
+	})
+	c.logger = &test_helper.LoggerMock{}
+	require.NoError(t, c.discovery.Start(ctx))
+	defer c.discovery.Stop(ctx)
+
+	conn, err := c.acquire(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, removedAddress, conn.RemoteAddr().String())
+	c.pool.Put(conn)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"endpoints": ["`+addedAddress+`"]}`), 0o600))
+	future := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, future, future))
+	require.Eventually(t, func() bool {
+		stats := c.balancer.Stats()
+
+		return len(stats) == 1 && stats[0].Address == addedAddress
+	}, time.Second, 10*time.Millisecond)
+
+	conn, err = c.acquire(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, addedAddress, conn.RemoteAddr().String())
+	c.pool.Discard(conn)
+}
+
 func TestClient_Stop(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		ctx := context.Background()
@@ -267,7 +439,7 @@ func Test_serv(t *testing.T) {
 
 	for _, tc := range testCaseList {
 		t.Run(tc.name, func(t *testing.T) {
-			err := handle(tc.args())
+			err := New(context.Background(), &config.Config{Verify: true}).handle(context.Background(), tc.args())
 			if tc.wantError {
 				assert.Error(t, err)
 
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/balancer"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/pkg/breaker"
+)
+
+var (
+	ErrUnknownOperation = errors.New("unknown operation")
+	ErrDeadlineExceeded = errors.New("deadline exceeded")
+	ErrCircuitOpen      = breaker.ErrOpen
+	ErrNoEndpoint       = balancer.ErrNoEndpoint
+)
+
+// ErrorClass returns short error category for reports
+func ErrorClass(err error) string {
+	var (
+		responseErr *ResponseError
+		mismatchErr *MismatchError
+		netErr      net.Error
+	)
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &mismatchErr):
+		return "mismatch"
+	case errors.Is(err, ErrDeadlineExceeded):
+		return "deadline"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrNoEndpoint):
+		return "no_endpoint"
+	case errors.As(err, &responseErr):
+		return "response"
+	case errors.As(err, &netErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "network"
+	default:
+		return "other"
+	}
+}
+
+// OperationError describes failed library call
+type OperationError struct {
+	Operation string
+	Err       error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation (%s): %v", e.Operation, e.Err)
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// ResponseError describes unexpected server response
+type ResponseError struct {
+	Response protocol.Response
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("server response: received wrong message (%v)", e.Response)
+}
+
+// MismatchError describes server result which differs from locally expected one
+type MismatchError struct {
+	Operation string
+	Args      []int64
+	Expected  int64
+	Received  int64
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("operation (%s) result mismatch: args %v, expected %d, received %d",
+		e.Operation, e.Args, e.Expected, e.Received)
+}
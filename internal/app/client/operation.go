@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/network"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+const OperationSum = "sum"
+
+// Operation performs single request/response exchange over connection
+type Operation func(ctx context.Context, conn io.ReadWriter, args []int64) (int64, error)
+
+// RegisterOperation makes operation available through Do, registered operation with the same name is replaced
+func (c *Client) RegisterOperation(name string, op Operation) {
+	c.operationMu.Lock()
+	defer c.operationMu.Unlock()
+
+	c.operationList[name] = op
+}
+
+// Do runs registered operation honouring context deadline
+func (c *Client) Do(ctx context.Context, name string, args []int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "internal.app.client.Client.Do")
+	defer span.End()
+
+	c.operationMu.RLock()
+	op, ok := c.operationList[name]
+	c.operationMu.RUnlock()
+	if !ok {
+		return 0, &OperationError{Operation: name, Err: ErrUnknownOperation}
+	}
+
+	conn, err := c.acquire(ctx)
+	if err != nil {
+		return 0, &OperationError{Operation: name, Err: errors.Wrap(err, "acquiring connection")}
+	}
+
+	var result int64
+	if err := c.exchange(ctx, conn, func(ctx context.Context, rw io.ReadWriter) error {
+		var err error
+		result, err = op(ctx, rw, args)
+
+		return err
+	}); err != nil {
+		return 0, &OperationError{Operation: name, Err: err}
+	}
+	if err := c.verify(name, args, result); err != nil {
+		return 0, &OperationError{Operation: name, Err: err}
+	}
+
+	return result, nil
+}
+
+// Sum returns sum of args calculated by server
+func (c *Client) Sum(ctx context.Context, args []int64) (int64, error) {
+	return c.Do(ctx, OperationSum, args)
+}
+
+func sum(ctx context.Context, conn io.ReadWriter, args []int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "internal.app.client.sum")
+	defer span.End()
+
+	request := protocol.Request{
+		Message: protocol.Message{
+			Type: protocol.MessageTypeRequest,
+		},
+		Payload: args,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		request.Deadline = deadline
+	}
+	if err := network.Send(ctx, conn, request); err != nil {
+		return 0, errors.Wrap(err, "sending request")
+	}
+
+	response, err := network.Receive[protocol.Response](ctx, conn)
+	if err != nil {
+		return 0, errors.Wrap(err, "receiving server response")
+	}
+	if response.Type == protocol.MessageTypeError && response.Error == protocol.ErrorDeadlineExceeded {
+		return 0, fmt.Errorf("%w: %w", ErrDeadlineExceeded, &ResponseError{Response: response})
+	}
+	if response.Type != protocol.MessageTypeResponse {
+		return 0, &ResponseError{Response: response}
+	}
+
+	return response.Payload, nil
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/network"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+)
+
+func TestClient_Do(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func() (context.Context, string, func(net.Conn))
+		wantResult int64
+		wantError  error
+	}{
+		{
+			name: "Sum",
+			args: func() (context.Context, string, func(net.Conn)) {
+				return context.Background(), OperationSum, serverStub(protocol.MessageTypeResponse)
+			},
+			wantResult: 6,
+		},
+		{
+			name: "Unknown operation",
+			args: func() (context.Context, string, func(net.Conn)) {
+				return context.Background(), "example", serverStub(protocol.MessageTypeResponse)
+			},
+			wantError: ErrUnknownOperation,
+		},
+		{
+			name: "Deadline exceeded",
+			args: func() (context.Context, string, func(net.Conn)) {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				t.Cleanup(cancel)
+
+				return ctx, OperationSum, func(net.Conn) {}
+			},
+			wantError: ErrDeadlineExceeded,
+		},
+		{
+			name: "Deadline exceeded on server",
+			args: func() (context.Context, string, func(net.Conn)) {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				t.Cleanup(cancel)
+
+				return ctx, OperationSum, func(conn net.Conn) {
+					defer func() {
+						_ = conn.Close()
+					}()
+
+					request, err := network.Receive[protocol.Request](ctx, conn)
+					if err != nil || request.Deadline.IsZero() {
+						return
+					}
+					_ = network.Send(ctx, conn, protocol.Response{
+						Message: protocol.Message{
+							Type: protocol.MessageTypeError,
+						},
+						Error: protocol.ErrorDeadlineExceeded,
+					})
+				}
+			},
+			wantError: ErrDeadlineExceeded,
+		},
+		{
+			name: "Wrong response",
+			args: func() (context.Context, string, func(net.Conn)) {
+				return context.Background(), OperationSum, serverStub(protocol.MessageTypeRequest)
+			},
+			wantError: &ResponseError{},
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, name, server := tc.args()
+
+			c := New(context.Background(), &config.Config{})
+			c.dialler = func(context.Context) (net.Conn, error) {
+				clientConn, serverConn := net.Pipe()
+				go server(serverConn)
+
+				return clientConn, nil
+			}
+
+			result, err := c.Do(ctx, name, []int64{1, 2, 3})
+			if tc.wantError != nil {
+				var operationErr *OperationError
+				require.ErrorAs(t, err, &operationErr)
+				assert.Equal(t, name, operationErr.Operation)
+
+				if responseErr, ok := tc.wantError.(*ResponseError); ok {
+					assert.ErrorAs(t, err, &responseErr)
+
+					return
+				}
+				assert.ErrorIs(t, err, tc.wantError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, result)
+		})
+	}
+}
+
+func TestClient_Sum(t *testing.T) {
+	t.Run("Circuit open", func(t *testing.T) {
+		c := New(context.Background(), &config.Config{
+			BreakerConsecutiveFailures: 1,
+			BreakerOpenTimeout:         time.Hour,
+		})
+		c.breaker.Done(assert.AnError)
+
+		_, err := c.Sum(context.Background(), []int64{1, 2, 3})
+
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+	})
+
+	t.Run("Deadline while connecting", func(t *testing.T) {
+		c := New(context.Background(), &config.Config{})
+		// blackholed endpoint never answers connection attempt
+		c.dialler = func(ctx context.Context) (net.Conn, error) {
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := c.Sum(ctx, []int64{1, 2, 3})
+
+		assert.ErrorIs(t, err, ErrDeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func serverStub(responseType protocol.MessageType) func(net.Conn) {
+	return func(conn net.Conn) {
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		ctx := context.Background()
+		request, err := network.Receive[protocol.Request](ctx, conn)
+		if err != nil {
+			return
+		}
+
+		var result int64
+		for _, arg := range request.Payload {
+			result += arg
+		}
+		_ = network.Send(ctx, conn, protocol.Response{
+			Message: protocol.Message{
+				Type: responseType,
+			},
+			Payload: result,
+		})
+	}
+}
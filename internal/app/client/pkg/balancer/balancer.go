@@ -0,0 +1,323 @@
+// Package balancer implements client side load balancing over server endpoints
+package balancer
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
+	"github.com/kirill-a-belov/test_task_framework/pkg/metrics"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+var ErrNoEndpoint = errors.New("no healthy endpoint")
+
+const (
+	StrategyRoundRobin       = "round_robin"
+	StrategyLeastOutstanding = "least_outstanding"
+	StrategyRandomTwo        = "random_two"
+)
+
+func New(ctx context.Context, config *config.Config) *Balancer {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.balancer.New")
+	defer span.End()
+
+	b := &Balancer{
+		config:   config,
+		stopChan: make(chan struct{}),
+		logger:   logger.New("client.balancer"),
+
+		checker: func(address string) error {
+			conn, err := net.DialTimeout(protocol.NetworkType, address, config.HealthCheckTimeout)
+			if err != nil {
+				return err
+			}
+
+			return conn.Close()
+		},
+		dialler: func(ctx context.Context, address string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, protocol.NetworkType, address)
+		},
+	}
+
+	switch config.BalancerStrategy {
+	case StrategyLeastOutstanding:
+		b.strategy = leastOutstanding
+	case StrategyRandomTwo:
+		b.strategy = randomTwo
+	default:
+		b.strategy = b.roundRobin
+	}
+
+	b.Update(config.Endpoints())
+
+	return b
+}
+
+type Balancer struct {
+	config   *config.Config
+	stopChan chan struct{}
+	logger   logger.Logger
+
+	mu           sync.RWMutex
+	endpointList []*Endpoint
+	nextPos      atomic.Uint64
+
+	strategy func([]*Endpoint) *Endpoint
+	checker  func(address string) error
+	dialler  func(ctx context.Context, address string) (net.Conn, error)
+}
+
+type Endpoint struct {
+	Address string
+
+	healthy        atomic.Bool
+	removed        atomic.Bool
+	checkFailedCnt int
+
+	outstanding    *metrics.Gauge
+	requestCounter *metrics.Counter
+	failureCounter *metrics.Counter
+}
+
+func newEndpoint(address string) *Endpoint {
+	e := &Endpoint{
+		Address: address,
+
+		outstanding:    metrics.NewGauge("client.endpoint." + address + ".outstanding"),
+		requestCounter: metrics.NewCounter("client.endpoint." + address + ".requests"),
+		failureCounter: metrics.NewCounter("client.endpoint." + address + ".failures"),
+	}
+	e.healthy.Store(true)
+
+	return e
+}
+
+// Begin marks request to endpoint as started
+func (e *Endpoint) Begin() {
+	e.outstanding.Add(1)
+	e.requestCounter.Inc()
+}
+
+// End marks request to endpoint as finished with result
+func (e *Endpoint) End(err error) {
+	e.outstanding.Add(-1)
+	if err != nil {
+		e.failureCounter.Inc()
+	}
+}
+
+func (e *Endpoint) Healthy() bool {
+	return e.healthy.Load()
+}
+
+// Removed reports whether endpoint is no longer in endpoint set, e.g. dropped by discovery
+func (e *Endpoint) Removed() bool {
+	return e.removed.Load()
+}
+
+type Stats struct {
+	Address     string
+	Healthy     bool
+	Outstanding int64
+	Requests    int64
+	Failures    int64
+}
+
+// Conn is a connection to balanced endpoint
+type Conn struct {
+	net.Conn
+	Endpoint *Endpoint
+}
+
+// NetConn returns underlying connection, e.g. for socket level health check
+func (c *Conn) NetConn() net.Conn {
+	return c.Conn
+}
+
+func (b *Balancer) Start(ctx context.Context) {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.balancer.Balancer.Start")
+	defer span.End()
+
+	if b.config.HealthCheckInterval > 0 {
+		go b.processor(ctx, b.config.HealthCheckInterval, b.check)
+	}
+	if b.config.StatsInterval > 0 {
+		go b.processor(ctx, b.config.StatsInterval, b.report)
+	}
+}
+
+func (b *Balancer) processor(ctx context.Context, interval time.Duration, interactor func()) {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.balancer.Balancer.processor")
+	defer span.End()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stopChan:
+			return
+		case <-ticker.C:
+			interactor()
+		}
+	}
+}
+
+func (b *Balancer) Stop(ctx context.Context) {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.balancer.Balancer.Stop")
+	defer span.End()
+
+	close(b.stopChan)
+}
+
+// Update replaces endpoint set keeping state of already known endpoints, dropped endpoints are marked removed
+func (b *Balancer) Update(addressList []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	knownList := make(map[string]*Endpoint, len(b.endpointList))
+	for _, e := range b.endpointList {
+		knownList[e.Address] = e
+	}
+
+	endpointList := make([]*Endpoint, 0, len(addressList))
+	for _, address := range addressList {
+		e, ok := knownList[address]
+		if !ok {
+			e = newEndpoint(address)
+		}
+		endpointList = append(endpointList, e)
+		delete(knownList, address)
+	}
+	for _, e := range knownList {
+		e.removed.Store(true)
+	}
+	b.endpointList = endpointList
+}
+
+// Pick selects healthy endpoint according to strategy
+func (b *Balancer) Pick() (*Endpoint, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	healthyList := make([]*Endpoint, 0, len(b.endpointList))
+	for _, e := range b.endpointList {
+		if e.Healthy() {
+			healthyList = append(healthyList, e)
+		}
+	}
+	if len(healthyList) == 0 {
+		return nil, ErrNoEndpoint
+	}
+
+	return b.strategy(healthyList), nil
+}
+
+// Dial connects to picked endpoint, connecting is interrupted when context is done
+func (b *Balancer) Dial(ctx context.Context) (net.Conn, error) {
+	e, err := b.Pick()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := b.dialler(ctx, e.Address)
+	if err != nil {
+		e.failureCounter.Inc()
+
+		return nil, errors.Wrapf(err, "dialing endpoint (%s)", e.Address)
+	}
+
+	return &Conn{
+		Conn:     conn,
+		Endpoint: e,
+	}, nil
+}
+
+func (b *Balancer) Stats() []Stats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	result := make([]Stats, 0, len(b.endpointList))
+	for _, e := range b.endpointList {
+		result = append(result, Stats{
+			Address:     e.Address,
+			Healthy:     e.Healthy(),
+			Outstanding: e.outstanding.Value(),
+			Requests:    e.requestCounter.Value(),
+			Failures:    e.failureCounter.Value(),
+		})
+	}
+
+	return result
+}
+
+func (b *Balancer) check() {
+	b.mu.RLock()
+	endpointList := append([]*Endpoint{}, b.endpointList...)
+	b.mu.RUnlock()
+
+	for _, e := range endpointList {
+		if err := b.checker(e.Address); err != nil {
+			e.checkFailedCnt++
+			if e.Healthy() && e.checkFailedCnt >= b.config.EjectFailures {
+				e.healthy.Store(false)
+				b.logger.Error(err, "endpoint ejected", e.Address)
+			}
+
+			continue
+		}
+
+		e.checkFailedCnt = 0
+		if !e.Healthy() {
+			e.healthy.Store(true)
+			b.logger.Info("endpoint readmitted", e.Address)
+		}
+	}
+}
+
+func (b *Balancer) report() {
+	for _, s := range b.Stats() {
+		b.logger.Info("endpoint stats",
+			"address", s.Address,
+			"healthy", s.Healthy,
+			"outstanding", s.Outstanding,
+			"requests", s.Requests,
+			"failures", s.Failures,
+		)
+	}
+}
+
+func (b *Balancer) roundRobin(endpointList []*Endpoint) *Endpoint {
+	return endpointList[(b.nextPos.Add(1)-1)%uint64(len(endpointList))]
+}
+
+func leastOutstanding(endpointList []*Endpoint) *Endpoint {
+	result := endpointList[0]
+	for _, e := range endpointList[1:] {
+		if e.outstanding.Value() < result.outstanding.Value() {
+			result = e
+		}
+	}
+
+	return result
+}
+
+// randomTwo picks less loaded of two random endpoints
+func randomTwo(endpointList []*Endpoint) *Endpoint {
+	first := endpointList[rand.Intn(len(endpointList))]
+	second := endpointList[rand.Intn(len(endpointList))]
+	if second.outstanding.Value() < first.outstanding.Value() {
+		return second
+	}
+
+	return first
+}
@@ -0,0 +1,223 @@
+package balancer
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/pkg/test_helper"
+)
+
+func TestBalancer_Pick(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func() *Balancer
+		wantResult []string
+		wantError  bool
+	}{
+		{
+			name: "Round robin",
+			args: func() *Balancer {
+				return New(context.Background(), &config.Config{
+					Address:     "rr-a:1",
+					AddressList: []string{"rr-b:1", "rr-c:1"},
+				})
+			},
+			wantResult: []string{"rr-a:1", "rr-b:1", "rr-c:1", "rr-a:1"},
+		},
+		{
+			name: "Least outstanding",
+			args: func() *Balancer {
+				b := New(context.Background(), &config.Config{
+					AddressList:      []string{"lo-a:1", "lo-b:1"},
+					BalancerStrategy: StrategyLeastOutstanding,
+				})
+				b.endpointList[0].Begin()
+
+				return b
+			},
+			wantResult: []string{"lo-b:1", "lo-b:1"},
+		},
+		{
+			name: "Random two choices",
+			args: func() *Balancer {
+				b := New(context.Background(), &config.Config{
+					AddressList:      []string{"r2-a:1"},
+					BalancerStrategy: StrategyRandomTwo,
+				})
+
+				return b
+			},
+			wantResult: []string{"r2-a:1", "r2-a:1"},
+		},
+		{
+			name: "Unhealthy skipped",
+			args: func() *Balancer {
+				b := New(context.Background(), &config.Config{
+					AddressList: []string{"uh-a:1", "uh-b:1"},
+				})
+				b.endpointList[0].healthy.Store(false)
+
+				return b
+			},
+			wantResult: []string{"uh-b:1", "uh-b:1"},
+		},
+		{
+			name: "No endpoint",
+			args: func() *Balancer {
+				return New(context.Background(), &config.Config{})
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			b := tc.args()
+
+			if tc.wantError {
+				_, err := b.Pick()
+				assert.ErrorIs(t, err, ErrNoEndpoint)
+
+				return
+			}
+
+			for _, want := range tc.wantResult {
+				e, err := b.Pick()
+				require.NoError(t, err)
+				assert.Equal(t, want, e.Address)
+			}
+		})
+	}
+}
+
+func TestBalancer_Update(t *testing.T) {
+	t.Run("Known endpoint state kept", func(t *testing.T) {
+		b := New(context.Background(), &config.Config{
+			AddressList: []string{"up-a:1", "up-b:1"},
+		})
+		b.endpointList[1].healthy.Store(false)
+
+		b.Update([]string{"up-b:1", "up-c:1"})
+
+		result := b.Stats()
+		require.Len(t, result, 2)
+		assert.Equal(t, "up-b:1", result[0].Address)
+		assert.False(t, result[0].Healthy)
+		assert.Equal(t, "up-c:1", result[1].Address)
+		assert.True(t, result[1].Healthy)
+	})
+
+	t.Run("Dropped endpoint removed", func(t *testing.T) {
+		b := New(context.Background(), &config.Config{
+			AddressList: []string{"rm-a:1", "rm-b:1"},
+		})
+		dropped, kept := b.endpointList[0], b.endpointList[1]
+
+		b.Update([]string{"rm-b:1"})
+		assert.True(t, dropped.Removed())
+		assert.False(t, kept.Removed())
+
+		b.Update([]string{"rm-a:1", "rm-b:1"})
+		assert.False(t, b.endpointList[0].Removed())
+		assert.NotSame(t, dropped, b.endpointList[0])
+	})
+}
+
+func TestBalancer_check(t *testing.T) {
+	t.Run("Eject and readmit", func(t *testing.T) {
+		loggerMock := &test_helper.LoggerMock{}
+		loggerMock.On("Error", mock.Anything, mock.Anything).Once()
+		loggerMock.On("Info", mock.Anything).Once()
+
+		b := New(context.Background(), &config.Config{
+			AddressList:   []string{"hc-a:1"},
+			EjectFailures: 2,
+		})
+		b.logger = loggerMock
+
+		var checkErr error = errors.New("example error")
+		b.checker = func(string) error {
+			return checkErr
+		}
+
+		b.check()
+		assert.True(t, b.endpointList[0].Healthy())
+		b.check()
+		assert.False(t, b.endpointList[0].Healthy())
+
+		checkErr = nil
+		b.check()
+		assert.True(t, b.endpointList[0].Healthy())
+		loggerMock.AssertExpectations(t)
+	})
+}
+
+func TestBalancer_Dial(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      func() (net.Conn, error)
+		wantError bool
+	}{
+		{
+			name: "Success",
+			args: func() (net.Conn, error) {
+				conn, _ := net.Pipe()
+
+				return conn, nil
+			},
+		},
+		{
+			name: "Dialing error",
+			args: func() (net.Conn, error) {
+				return nil, errors.New("example error")
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			b := New(context.Background(), &config.Config{
+				Address: "dial-a:1",
+			})
+			b.dialler = func(context.Context, string) (net.Conn, error) {
+				return tc.args()
+			}
+
+			conn, err := b.Dial(context.Background())
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "dial-a:1", conn.(*Conn).Endpoint.Address)
+		})
+	}
+}
+
+func TestBalancer_Dial_context(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	b := New(context.Background(), &config.Config{
+		Address: listener.Addr().String(),
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = b.Dial(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -8,19 +8,105 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
 
+	"github.com/kirill-a-belov/test_task_framework/pkg/backoff"
+	"github.com/kirill-a-belov/test_task_framework/pkg/breaker"
 	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
 )
 
+const (
+	ModeLoop = "loop"
+	ModeLoad = "load"
+)
+
 type Config struct {
-	Address string        `env:"CLIENT_ADDRESS" validate:"hostname_port"`
+	Mode   string `env:"CLIENT_MODE" validate:"omitempty,oneof=loop load"`
+	Verify bool   `env:"CLIENT_VERIFY"`
+
+	Address string        `env:"CLIENT_ADDRESS" validate:"required_without_all=AddressList DiscoverySRV DiscoveryFile,omitempty,hostname_port"`
 	Delay   time.Duration `env:"CLIENT_DELAY" validate:"gte=1ms,lte=1s"`
 	ConnTTL time.Duration `env:"CLIENT_CONN_TTL" validate:"gte=1ms,lte=1s"`
+
+	AddressList         []string      `env:"CLIENT_ADDRESS_LIST" validate:"dive,hostname_port"`
+	BalancerStrategy    string        `env:"CLIENT_BALANCER_STRATEGY" validate:"omitempty,oneof=round_robin least_outstanding random_two"`
+	HealthCheckInterval time.Duration `env:"CLIENT_HEALTH_CHECK_INTERVAL" validate:"gte=0"`
+	HealthCheckTimeout  time.Duration `env:"CLIENT_HEALTH_CHECK_TIMEOUT" envDefault:"100ms" validate:"gte=0"`
+	EjectFailures       int           `env:"CLIENT_EJECT_FAILURES" envDefault:"3" validate:"gte=0"`
+	StatsInterval       time.Duration `env:"CLIENT_STATS_INTERVAL" validate:"gte=0"`
+
+	DiscoverySRV          string        `env:"CLIENT_DISCOVERY_SRV"`
+	DiscoveryInterval     time.Duration `env:"CLIENT_DISCOVERY_INTERVAL" envDefault:"30s" validate:"required_with=DiscoverySRV,omitempty,gte=1s"`
+	DiscoveryFile         string        `env:"CLIENT_DISCOVERY_FILE"`
+	DiscoveryFileInterval time.Duration `env:"CLIENT_DISCOVERY_FILE_INTERVAL" envDefault:"1s" validate:"required_with=DiscoveryFile,omitempty,gte=10ms"`
+
+	PoolMinIdle     int           `env:"CLIENT_POOL_MIN_IDLE" validate:"gte=0,ltefield=PoolMaxIdle"`
+	PoolMaxIdle     int           `env:"CLIENT_POOL_MAX_IDLE" validate:"gte=0,lte=1024"`
+	PoolMaxLifetime time.Duration `env:"CLIENT_POOL_MAX_LIFETIME" validate:"gte=0"`
+
+	RetryBase        time.Duration `env:"CLIENT_RETRY_BASE" envDefault:"100ms" validate:"gt=0"`
+	RetryMax         time.Duration `env:"CLIENT_RETRY_MAX" envDefault:"5s" validate:"gtefield=RetryBase"`
+	RetryJitter      float64       `env:"CLIENT_RETRY_JITTER" envDefault:"0.2" validate:"gte=0,lte=1"`
+	RetryMaxAttempts int           `env:"CLIENT_RETRY_MAX_ATTEMPTS" envDefault:"3" validate:"gte=0,lte=100"`
+
+	BreakerWindowSize          int           `env:"CLIENT_BREAKER_WINDOW_SIZE" validate:"gte=0,lte=10000"`
+	BreakerFailureRatio        float64       `env:"CLIENT_BREAKER_FAILURE_RATIO" envDefault:"0.5" validate:"gte=0,lte=1"`
+	BreakerMinRequests         int           `env:"CLIENT_BREAKER_MIN_REQUESTS" envDefault:"10" validate:"gte=0"`
+	BreakerConsecutiveFailures int           `env:"CLIENT_BREAKER_CONSECUTIVE_FAILURES" validate:"gte=0"`
+	BreakerOpenTimeout         time.Duration `env:"CLIENT_BREAKER_OPEN_TIMEOUT" envDefault:"5s" validate:"gte=0"`
+	BreakerHalfOpenRequests    int           `env:"CLIENT_BREAKER_HALF_OPEN_REQUESTS" envDefault:"1" validate:"gte=0"`
+
+	LoadConcurrency  int           `env:"CLIENT_LOAD_CONCURRENCY" envDefault:"1" validate:"gte=0,lte=10000"`
+	LoadRPS          float64       `env:"CLIENT_LOAD_RPS" validate:"gte=0"`
+	LoadDuration     time.Duration `env:"CLIENT_LOAD_DURATION" envDefault:"10s" validate:"gte=0"`
+	LoadWarmUp       time.Duration `env:"CLIENT_LOAD_WARM_UP" validate:"gte=0"`
+	LoadReportFormat string        `env:"CLIENT_LOAD_REPORT_FORMAT" validate:"omitempty,oneof=text json"`
+
+	PayloadGenerator        string `env:"CLIENT_PAYLOAD_GENERATOR" validate:"omitempty,oneof=random edge file"`
+	PayloadSizeDistribution string `env:"CLIENT_PAYLOAD_SIZE_DISTRIBUTION" validate:"omitempty,oneof=fixed uniform"`
+	PayloadSize             int    `env:"CLIENT_PAYLOAD_SIZE" envDefault:"3" validate:"min=1,lte=100000"`
+	PayloadMinSize          int    `env:"CLIENT_PAYLOAD_MIN_SIZE" envDefault:"1" validate:"min=1,ltefield=PayloadSize"`
+	PayloadMinValue         int64  `env:"CLIENT_PAYLOAD_MIN_VALUE"`
+	PayloadMaxValue         int64  `env:"CLIENT_PAYLOAD_MAX_VALUE" envDefault:"1024" validate:"gtefield=PayloadMinValue"`
+	PayloadSeed             int64  `env:"CLIENT_PAYLOAD_SEED"`
+	PayloadFile             string `env:"CLIENT_PAYLOAD_FILE" validate:"required_if=PayloadGenerator file"`
+
+	// MetricsInterval is period of metrics logging, zero disables it
+	MetricsInterval time.Duration `env:"CLIENT_METRICS_INTERVAL" envDefault:"1m" validate:"gte=0"`
 }
 
 func (c *Config) validate() error {
 	return validator.New(validator.WithRequiredStructEnabled()).Struct(c)
 }
 
+// Endpoints returns all configured server addresses
+func (c *Config) Endpoints() []string {
+	result := make([]string, 0, len(c.AddressList)+1)
+	if c.Address != "" {
+		result = append(result, c.Address)
+	}
+
+	return append(result, c.AddressList...)
+}
+
+func (c *Config) RetryPolicy() backoff.Policy {
+	return backoff.Policy{
+		Base:        c.RetryBase,
+		Max:         c.RetryMax,
+		Jitter:      c.RetryJitter,
+		MaxAttempts: c.RetryMaxAttempts,
+	}
+}
+
+func (c *Config) BreakerPolicy() breaker.Policy {
+	return breaker.Policy{
+		WindowSize:          c.BreakerWindowSize,
+		FailureRatio:        c.BreakerFailureRatio,
+		MinRequests:         c.BreakerMinRequests,
+		ConsecutiveFailures: c.BreakerConsecutiveFailures,
+		OpenTimeout:         c.BreakerOpenTimeout,
+		HalfOpenRequests:    c.BreakerHalfOpenRequests,
+	}
+}
+
 func (c *Config) Load(ctx context.Context) error {
 	_, span := tracer.Start(ctx, "client.Config.Load")
 	defer span.End()
@@ -16,36 +16,364 @@ func TestConfig_validate(t *testing.T) {
 		{
 			name: "Success",
 			args: Config{
-				Address: "localhost:1234",
-				Delay:   time.Second,
-				ConnTTL: time.Second,
+				Address:        "localhost:1234",
+				Delay:          time.Second,
+				ConnTTL:        time.Second,
+				PayloadSize:    3,
+				PayloadMinSize: 1,
+				RetryBase:      100 * time.Millisecond,
+				RetryMax:       time.Second,
 			},
 			wantError: false,
 		},
 		{
 			name: "Invalid address",
 			args: Config{
-				Address: "local:host:1234",
-				Delay:   time.Second,
-				ConnTTL: time.Second,
+				Address:   "local:host:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
 			},
 			wantError: true,
 		},
 		{
 			name: "Invalid delay value",
 			args: Config{
-				Address: "localhost:1234",
-				Delay:   time.Hour,
-				ConnTTL: time.Second,
+				Address:   "localhost:1234",
+				Delay:     time.Hour,
+				ConnTTL:   time.Second,
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
 			},
 			wantError: true,
 		},
 		{
 			name: "Invalid TTL",
 			args: Config{
-				Address: "localhost:1234",
-				Delay:   time.Second,
-				ConnTTL: time.Hour,
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Hour,
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with pool",
+			args: Config{
+				Address:         "localhost:1234",
+				Delay:           time.Second,
+				ConnTTL:         time.Second,
+				PoolMinIdle:     1,
+				PoolMaxIdle:     10,
+				PoolMaxLifetime: time.Minute,
+				PayloadSize:     3,
+				PayloadMinSize:  1,
+				RetryBase:       100 * time.Millisecond,
+				RetryMax:        time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Pool min idle above max idle",
+			args: Config{
+				Address:     "localhost:1234",
+				Delay:       time.Second,
+				ConnTTL:     time.Second,
+				PoolMinIdle: 10,
+				PoolMaxIdle: 1,
+				RetryBase:   100 * time.Millisecond,
+				RetryMax:    time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Negative pool max lifetime",
+			args: Config{
+				Address:         "localhost:1234",
+				Delay:           time.Second,
+				ConnTTL:         time.Second,
+				PoolMaxLifetime: -time.Second,
+				RetryBase:       100 * time.Millisecond,
+				RetryMax:        time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with retry policy",
+			args: Config{
+				Address:          "localhost:1234",
+				Delay:            time.Second,
+				ConnTTL:          time.Second,
+				RetryBase:        time.Millisecond,
+				RetryMax:         time.Second,
+				RetryJitter:      0.5,
+				RetryMaxAttempts: 3,
+				PayloadSize:      3,
+				PayloadMinSize:   1,
+			},
+			wantError: false,
+		},
+		{
+			name: "Retry max below base",
+			args: Config{
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				RetryBase: time.Second,
+				RetryMax:  time.Millisecond,
+			},
+			wantError: true,
+		},
+		{
+			name: "Zero retry base",
+			args: Config{
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				RetryBase: 0,
+				RetryMax:  time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid retry jitter",
+			args: Config{
+				Address:     "localhost:1234",
+				Delay:       time.Second,
+				ConnTTL:     time.Second,
+				RetryJitter: 2,
+				RetryBase:   100 * time.Millisecond,
+				RetryMax:    time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with circuit breaker",
+			args: Config{
+				Address:                    "localhost:1234",
+				Delay:                      time.Second,
+				ConnTTL:                    time.Second,
+				BreakerWindowSize:          100,
+				BreakerFailureRatio:        0.5,
+				BreakerMinRequests:         10,
+				BreakerConsecutiveFailures: 5,
+				BreakerOpenTimeout:         time.Second,
+				BreakerHalfOpenRequests:    1,
+				PayloadSize:                3,
+				PayloadMinSize:             1,
+				RetryBase:                  100 * time.Millisecond,
+				RetryMax:                   time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Invalid breaker failure ratio",
+			args: Config{
+				Address:             "localhost:1234",
+				Delay:               time.Second,
+				ConnTTL:             time.Second,
+				BreakerFailureRatio: 1.5,
+				RetryBase:           100 * time.Millisecond,
+				RetryMax:            time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with address list",
+			args: Config{
+				AddressList:         []string{"localhost:1234", "localhost:1235"},
+				BalancerStrategy:    "least_outstanding",
+				HealthCheckInterval: time.Second,
+				Delay:               time.Second,
+				ConnTTL:             time.Second,
+				PayloadSize:         3,
+				PayloadMinSize:      1,
+				RetryBase:           100 * time.Millisecond,
+				RetryMax:            time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Missing address",
+			args: Config{
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid address in list",
+			args: Config{
+				AddressList: []string{"localhost:1234", "local:host:1234"},
+				Delay:       time.Second,
+				ConnTTL:     time.Second,
+				RetryBase:   100 * time.Millisecond,
+				RetryMax:    time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid balancer strategy",
+			args: Config{
+				Address:          "localhost:1234",
+				BalancerStrategy: "example",
+				Delay:            time.Second,
+				ConnTTL:          time.Second,
+				RetryBase:        100 * time.Millisecond,
+				RetryMax:         time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with load mode",
+			args: Config{
+				Mode:             ModeLoad,
+				Address:          "localhost:1234",
+				Delay:            time.Second,
+				ConnTTL:          time.Second,
+				LoadConcurrency:  10,
+				LoadRPS:          100,
+				LoadDuration:     time.Minute,
+				LoadWarmUp:       time.Second,
+				LoadReportFormat: "json",
+				PayloadSize:      3,
+				PayloadMinSize:   1,
+				RetryBase:        100 * time.Millisecond,
+				RetryMax:         time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Invalid mode",
+			args: Config{
+				Mode:      "example",
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with discovery",
+			args: Config{
+				Delay:                 time.Second,
+				ConnTTL:               time.Second,
+				DiscoverySRV:          "_sum._tcp.example.com",
+				DiscoveryInterval:     time.Minute,
+				DiscoveryFile:         "endpoints.yaml",
+				DiscoveryFileInterval: time.Second,
+				PayloadSize:           3,
+				PayloadMinSize:        1,
+				RetryBase:             100 * time.Millisecond,
+				RetryMax:              time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Missing endpoints",
+			args: Config{
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid discovery interval",
+			args: Config{
+				Delay:             time.Second,
+				ConnTTL:           time.Second,
+				DiscoverySRV:      "_sum._tcp.example.com",
+				DiscoveryInterval: time.Millisecond,
+				RetryBase:         100 * time.Millisecond,
+				RetryMax:          time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with payload generator",
+			args: Config{
+				Address:                 "localhost:1234",
+				Delay:                   time.Second,
+				ConnTTL:                 time.Second,
+				PayloadGenerator:        "edge",
+				PayloadSizeDistribution: "uniform",
+				PayloadSize:             10,
+				PayloadMinSize:          1,
+				PayloadMinValue:         -1024,
+				PayloadMaxValue:         1024,
+				PayloadSeed:             42,
+				RetryBase:               100 * time.Millisecond,
+				RetryMax:                time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Invalid payload size range",
+			args: Config{
+				Address:        "localhost:1234",
+				Delay:          time.Second,
+				ConnTTL:        time.Second,
+				PayloadSize:    1,
+				PayloadMinSize: 10,
+				RetryBase:      100 * time.Millisecond,
+				RetryMax:       time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Zero payload min size",
+			args: Config{
+				Address:     "localhost:1234",
+				Delay:       time.Second,
+				ConnTTL:     time.Second,
+				PayloadSize: 3,
+				RetryBase:   100 * time.Millisecond,
+				RetryMax:    time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid payload value range",
+			args: Config{
+				Address:         "localhost:1234",
+				Delay:           time.Second,
+				ConnTTL:         time.Second,
+				PayloadMinValue: 10,
+				PayloadMaxValue: 1,
+				RetryBase:       100 * time.Millisecond,
+				RetryMax:        time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Payload file missing",
+			args: Config{
+				Address:          "localhost:1234",
+				Delay:            time.Second,
+				ConnTTL:          time.Second,
+				PayloadGenerator: "file",
+				RetryBase:        100 * time.Millisecond,
+				RetryMax:         time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid report format",
+			args: Config{
+				Address:          "localhost:1234",
+				Delay:            time.Second,
+				ConnTTL:          time.Second,
+				LoadReportFormat: "example",
+				RetryBase:        100 * time.Millisecond,
+				RetryMax:         time.Second,
 			},
 			wantError: true,
 		},
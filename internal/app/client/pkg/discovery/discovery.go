@@ -0,0 +1,200 @@
+// Package discovery implements server endpoint discovery via DNS SRV records and endpoints file
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/pkg/file_watcher"
+	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+const (
+	sourceStatic = "static"
+	sourceSRV    = "srv"
+	sourceFile   = "file"
+)
+
+// Resolver looks up DNS SRV records, implemented by net.Resolver
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// New returns discovery reporting merged endpoint list of configured sources to update
+func New(ctx context.Context, config *config.Config, update func(addressList []string)) *Discovery {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.discovery.New")
+	defer span.End()
+
+	return &Discovery{
+		config:   config,
+		stopChan: make(chan struct{}),
+		logger:   logger.New("client.discovery"),
+		update:   update,
+		resolver: net.DefaultResolver,
+
+		sourceList: map[string][]string{
+			sourceStatic: config.Endpoints(),
+		},
+	}
+}
+
+type Discovery struct {
+	config   *config.Config
+	stopChan chan struct{}
+	logger   logger.Logger
+	update   func(addressList []string)
+	resolver Resolver
+	watcher  *file_watcher.Watcher
+
+	mu          sync.Mutex
+	sourceList  map[string][]string
+	addressList []string
+}
+
+// Start resolves configured sources synchronously and then keeps them up to date
+func (d *Discovery) Start(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "internal.app.client.pkg.discovery.Discovery.Start")
+	defer span.End()
+
+	if d.config.DiscoverySRV != "" {
+		if err := d.resolve(ctx); err != nil {
+			return errors.Wrap(err, "resolving SRV record")
+		}
+		go d.processor(ctx)
+	}
+	if d.config.DiscoveryFile != "" {
+		d.watcher = file_watcher.New(d.config.DiscoveryFile, d.config.DiscoveryFileInterval, d.load)
+		if err := d.watcher.Start(ctx); err != nil {
+			return errors.Wrap(err, "starting endpoints file watcher")
+		}
+	}
+
+	return nil
+}
+
+func (d *Discovery) processor(ctx context.Context) {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.discovery.Discovery.processor")
+	defer span.End()
+
+	ticker := time.NewTicker(d.config.DiscoveryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stopChan:
+			return
+		case <-ticker.C:
+			if err := d.resolve(ctx); err != nil {
+				d.logger.Error(err, "resolving SRV record", "name", d.config.DiscoverySRV)
+			}
+		}
+	}
+}
+
+func (d *Discovery) Stop(ctx context.Context) {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.discovery.Discovery.Stop")
+	defer span.End()
+
+	if d.watcher != nil {
+		d.watcher.Stop(ctx)
+	}
+	close(d.stopChan)
+}
+
+// resolve looks up SRV record, only targets with the lowest priority are used,
+// on failure last known targets are kept
+func (d *Discovery) resolve(ctx context.Context) error {
+	if d.config.DiscoveryInterval > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.config.DiscoveryInterval)
+		defer cancel()
+	}
+
+	_, srvList, err := d.resolver.LookupSRV(ctx, "", "", d.config.DiscoverySRV)
+	if err != nil {
+		return err
+	}
+
+	var addressList []string
+	for _, srv := range srvList {
+		if len(addressList) > 0 && srv.Priority != srvList[0].Priority {
+			break
+		}
+		addressList = append(addressList, net.JoinHostPort(
+			strings.TrimSuffix(srv.Target, "."),
+			strconv.Itoa(int(srv.Port)),
+		))
+	}
+	d.set(sourceSRV, addressList)
+
+	return nil
+}
+
+type endpointsFile struct {
+	Endpoints []string `json:"endpoints" yaml:"endpoints"`
+}
+
+// load parses endpoints file, YAML format is chosen by .yaml or .yml extension, JSON otherwise
+func (d *Discovery) load(data []byte) error {
+	var f endpointsFile
+	switch strings.ToLower(filepath.Ext(d.config.DiscoveryFile)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return errors.Wrap(err, "parsing endpoints file")
+		}
+	default:
+		if err := json.Unmarshal(data, &f); err != nil {
+			return errors.Wrap(err, "parsing endpoints file")
+		}
+	}
+
+	for _, address := range f.Endpoints {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return errors.Wrapf(err, "invalid endpoint (%s)", address)
+		}
+	}
+	d.set(sourceFile, f.Endpoints)
+
+	return nil
+}
+
+// set replaces source addresses and reports merged list if it has changed
+func (d *Discovery) set(source string, addressList []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sourceList[source] = addressList
+
+	known := make(map[string]struct{})
+	merged := make([]string, 0, len(d.addressList))
+	for _, sourceAddressList := range d.sourceList {
+		for _, address := range sourceAddressList {
+			if _, ok := known[address]; ok {
+				continue
+			}
+			known[address] = struct{}{}
+			merged = append(merged, address)
+		}
+	}
+	sort.Strings(merged)
+
+	if reflect.DeepEqual(merged, d.addressList) {
+		return
+	}
+	d.addressList = merged
+	d.logger.Info("endpoints updated", "source", source, "endpoints", merged)
+	d.update(merged)
+}
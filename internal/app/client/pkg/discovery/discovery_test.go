@@ -0,0 +1,215 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+)
+
+type resolverStub struct {
+	mu      sync.Mutex
+	srvList []*net.SRV
+	err     error
+}
+
+func (r *resolverStub) LookupSRV(context.Context, string, string, string) (string, []*net.SRV, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return "", r.srvList, r.err
+}
+
+func (r *resolverStub) set(srvList []*net.SRV, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.srvList, r.err = srvList, err
+}
+
+type updateRecorder struct {
+	mu         sync.Mutex
+	updateList [][]string
+}
+
+func (u *updateRecorder) update(addressList []string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.updateList = append(u.updateList, addressList)
+}
+
+func (u *updateRecorder) last() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(u.updateList) == 0 {
+		return nil
+	}
+
+	return u.updateList[len(u.updateList)-1]
+}
+
+func TestDiscovery_resolve(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func() ([]*net.SRV, error)
+		wantResult []string
+		wantError  bool
+	}{
+		{
+			name: "Lowest priority targets merged with static",
+			args: func() ([]*net.SRV, error) {
+				return []*net.SRV{
+					{Target: "b.example.", Port: 1234, Priority: 1},
+					{Target: "a.example.", Port: 1234, Priority: 1},
+					{Target: "c.example.", Port: 1234, Priority: 2},
+				}, nil
+			},
+			wantResult: []string{"a.example:1234", "b.example:1234", "localhost:1234"},
+		},
+		{
+			name: "Lookup error",
+			args: func() ([]*net.SRV, error) {
+				return nil, errors.New("example error")
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := &updateRecorder{}
+			d := New(context.Background(), &config.Config{
+				Address:      "localhost:1234",
+				DiscoverySRV: "_sum._tcp.example",
+			}, recorder.update)
+			resolver := &resolverStub{}
+			resolver.set(tc.args())
+			d.resolver = resolver
+
+			err := d.resolve(context.Background())
+			if tc.wantError {
+				assert.Error(t, err)
+				assert.Nil(t, recorder.last())
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, recorder.last())
+		})
+	}
+}
+
+func TestDiscovery_load(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func() (string, string)
+		wantResult []string
+		wantError  bool
+	}{
+		{
+			name: "JSON",
+			args: func() (string, string) {
+				return "endpoints.json", `{"endpoints": ["b.example:1234", "a.example:1234"]}`
+			},
+			wantResult: []string{"a.example:1234", "b.example:1234"},
+		},
+		{
+			name: "YAML",
+			args: func() (string, string) {
+				return "endpoints.yaml", "endpoints:\n  - a.example:1234\n  - a.example:1234\n"
+			},
+			wantResult: []string{"a.example:1234"},
+		},
+		{
+			name: "Invalid endpoint",
+			args: func() (string, string) {
+				return "endpoints.json", `{"endpoints": ["example"]}`
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid format",
+			args: func() (string, string) {
+				return "endpoints.yml", "endpoints: {"
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			path, data := tc.args()
+			recorder := &updateRecorder{}
+			d := New(context.Background(), &config.Config{DiscoveryFile: path}, recorder.update)
+
+			err := d.load([]byte(data))
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, recorder.last())
+		})
+	}
+}
+
+func TestDiscovery_Start(t *testing.T) {
+	t.Run("Live updates", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "endpoints.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"endpoints": ["a.example:1234"]}`), 0o600))
+
+		recorder := &updateRecorder{}
+		d := New(context.Background(), &config.Config{
+			DiscoverySRV:          "_sum._tcp.example",
+			DiscoveryInterval:     10 * time.Millisecond,
+			DiscoveryFile:         path,
+			DiscoveryFileInterval: 10 * time.Millisecond,
+		}, recorder.update)
+		resolver := &resolverStub{}
+		resolver.set([]*net.SRV{{Target: "b.example.", Port: 1234}}, nil)
+		d.resolver = resolver
+
+		require.NoError(t, d.Start(context.Background()))
+		defer d.Stop(context.Background())
+		assert.Equal(t, []string{"a.example:1234", "b.example:1234"}, recorder.last())
+
+		resolver.set(nil, errors.New("example error"))
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, []string{"a.example:1234", "b.example:1234"}, recorder.last())
+
+		resolver.set([]*net.SRV{{Target: "c.example.", Port: 1234}}, nil)
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual([]string{"a.example:1234", "c.example:1234"}, recorder.last())
+		}, time.Second, 10*time.Millisecond)
+
+		require.NoError(t, os.WriteFile(path, []byte(`{"endpoints": ["d.example:1234"]}`), 0o600))
+		future := time.Now().Add(time.Second)
+		require.NoError(t, os.Chtimes(path, future, future))
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual([]string{"c.example:1234", "d.example:1234"}, recorder.last())
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		d := New(context.Background(), &config.Config{
+			DiscoveryFile:         filepath.Join(t.TempDir(), "endpoints.json"),
+			DiscoveryFileInterval: time.Hour,
+		}, func([]string) {})
+
+		assert.Error(t, d.Start(context.Background()))
+	})
+}
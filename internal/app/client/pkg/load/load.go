@@ -0,0 +1,290 @@
+// Package load implements load generator with latency report
+package load
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/pkg/backoff"
+	"github.com/kirill-a-belov/test_task_framework/pkg/metrics"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+const (
+	ReportFormatText = "text"
+	ReportFormatJSON = "json"
+)
+
+func New(
+	ctx context.Context,
+	config *config.Config,
+	request func(context.Context) error,
+	classify func(error) string,
+) *Generator {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.load.New")
+	defer span.End()
+
+	return &Generator{
+		config:   config,
+		request:  request,
+		classify: classify,
+
+		histogram:       metrics.NewHistogram(),
+		errorCntByClass: make(map[string]int64),
+	}
+}
+
+type Generator struct {
+	config   *config.Config
+	request  func(context.Context) error
+	classify func(error) string
+
+	measureFrom     time.Time
+	histogram       *metrics.Histogram
+	droppedCnt      atomic.Int64
+	mu              sync.Mutex
+	errorCntByClass map[string]int64
+}
+
+// Run generates load until configured duration elapses or context is done
+func (g *Generator) Run(ctx context.Context) *Report {
+	ctx, span := tracer.Start(ctx, "internal.app.client.pkg.load.Generator.Run")
+	defer span.End()
+
+	startedAt := time.Now()
+	g.measureFrom = startedAt.Add(g.config.LoadWarmUp)
+	if g.config.LoadDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, g.measureFrom.Add(g.config.LoadDuration))
+		defer cancel()
+	}
+
+	concurrency := g.config.LoadConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if g.config.LoadRPS > 0 {
+		g.openLoop(ctx, concurrency, startedAt)
+	} else {
+		g.closedLoop(ctx, concurrency)
+	}
+
+	return g.report(time.Since(g.measureFrom))
+}
+
+// closedLoop runs requests back to back from every worker
+func (g *Generator) closedLoop(ctx context.Context, concurrency int) {
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				g.do(ctx, time.Now())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// openLoop schedules requests at fixed rate, latency is measured from scheduled time
+func (g *Generator) openLoop(ctx context.Context, concurrency int, startedAt time.Time) {
+	scheduleChan := make(chan time.Time, concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for scheduledAt := range scheduleChan {
+				g.do(ctx, scheduledAt)
+			}
+		}()
+	}
+
+	interval := float64(time.Second) / g.config.LoadRPS
+	for n := 0; ctx.Err() == nil; n++ {
+		scheduledAt := startedAt.Add(time.Duration(float64(n) * interval))
+		if err := backoff.Wait(ctx, time.Until(scheduledAt)); err != nil {
+			break
+		}
+
+		select {
+		case scheduleChan <- scheduledAt:
+		default:
+			if !scheduledAt.Before(g.measureFrom) {
+				g.droppedCnt.Add(1)
+			}
+		}
+	}
+
+	close(scheduleChan)
+	wg.Wait()
+}
+
+func (g *Generator) do(ctx context.Context, scheduledAt time.Time) {
+	err := g.request(ctx)
+	latency := time.Since(scheduledAt)
+
+	if scheduledAt.Before(g.measureFrom) || ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		g.mu.Lock()
+		g.errorCntByClass[g.classify(err)]++
+		g.mu.Unlock()
+
+		return
+	}
+	g.histogram.Observe(latency)
+}
+
+type Report struct {
+	Duration  time.Duration
+	Successes int64
+	Dropped   int64
+	Errors    map[string]int64
+	Latency   Latency
+}
+
+type Latency struct {
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	P999 time.Duration
+	Max  time.Duration
+}
+
+func (g *Generator) report(duration time.Duration) *Report {
+	if duration < 0 {
+		duration = 0
+	}
+
+	g.mu.Lock()
+	errorCntByClass := make(map[string]int64, len(g.errorCntByClass))
+	for class, cnt := range g.errorCntByClass {
+		errorCntByClass[class] = cnt
+	}
+	g.mu.Unlock()
+
+	return &Report{
+		Duration:  duration,
+		Successes: g.histogram.Count(),
+		Dropped:   g.droppedCnt.Load(),
+		Errors:    errorCntByClass,
+		Latency: Latency{
+			Min:  g.histogram.Min(),
+			Mean: g.histogram.Mean(),
+			P50:  g.histogram.Quantile(0.5),
+			P90:  g.histogram.Quantile(0.9),
+			P99:  g.histogram.Quantile(0.99),
+			P999: g.histogram.Quantile(0.999),
+			Max:  g.histogram.Max(),
+		},
+	}
+}
+
+func (r *Report) ErrorCount() int64 {
+	var result int64
+	for _, cnt := range r.Errors {
+		result += cnt
+	}
+
+	return result
+}
+
+// Throughput returns successful requests per second
+func (r *Report) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+
+	return float64(r.Successes) / r.Duration.Seconds()
+}
+
+func (r *Report) Write(w io.Writer, format string) error {
+	if format == ReportFormatJSON {
+		return r.writeJSON(w)
+	}
+
+	return r.writeText(w)
+}
+
+func (r *Report) writeText(w io.Writer) error {
+	classList := make([]string, 0, len(r.Errors))
+	for class := range r.Errors {
+		classList = append(classList, class)
+	}
+	sort.Strings(classList)
+
+	errorList := ""
+	for _, class := range classList {
+		errorList += fmt.Sprintf(" %s=%d", class, r.Errors[class])
+	}
+
+	if _, err := fmt.Fprintf(w,
+		"duration: %v\n"+
+			"requests: %d (errors: %d, dropped: %d)\n"+
+			"throughput: %.2f rps\n"+
+			"latency: min=%v mean=%v p50=%v p90=%v p99=%v p999=%v max=%v\n"+
+			"errors:%s\n",
+		r.Duration.Round(time.Millisecond),
+		r.Successes+r.ErrorCount(), r.ErrorCount(), r.Dropped,
+		r.Throughput(),
+		r.Latency.Min, r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.P999, r.Latency.Max,
+		errorList,
+	); err != nil {
+		return errors.Wrap(err, "writing text report")
+	}
+
+	return nil
+}
+
+func (r *Report) writeJSON(w io.Writer) error {
+	toMs := func(d time.Duration) float64 {
+		return float64(d) / float64(time.Millisecond)
+	}
+
+	if err := json.NewEncoder(w).Encode(struct {
+		DurationSec float64            `json:"duration_sec"`
+		Requests    int64              `json:"requests"`
+		Successes   int64              `json:"successes"`
+		Dropped     int64              `json:"dropped"`
+		Throughput  float64            `json:"throughput_rps"`
+		Errors      map[string]int64   `json:"errors"`
+		LatencyMs   map[string]float64 `json:"latency_ms"`
+	}{
+		DurationSec: r.Duration.Seconds(),
+		Requests:    r.Successes + r.ErrorCount(),
+		Successes:   r.Successes,
+		Dropped:     r.Dropped,
+		Throughput:  r.Throughput(),
+		Errors:      r.Errors,
+		LatencyMs: map[string]float64{
+			"min":  toMs(r.Latency.Min),
+			"mean": toMs(r.Latency.Mean),
+			"p50":  toMs(r.Latency.P50),
+			"p90":  toMs(r.Latency.P90),
+			"p99":  toMs(r.Latency.P99),
+			"p999": toMs(r.Latency.P999),
+			"max":  toMs(r.Latency.Max),
+		},
+	}); err != nil {
+		return errors.Wrap(err, "writing JSON report")
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package load
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+)
+
+func TestGenerator_Run(t *testing.T) {
+	testCaseList := []struct {
+		name  string
+		args  func() (*config.Config, func(context.Context) error)
+		check func(t *testing.T, r *Report)
+	}{
+		{
+			name: "Closed loop",
+			args: func() (*config.Config, func(context.Context) error) {
+				return &config.Config{
+					LoadConcurrency: 2,
+					LoadDuration:    50 * time.Millisecond,
+				}, func(context.Context) error {
+					time.Sleep(time.Millisecond)
+
+					return nil
+				}
+			},
+			check: func(t *testing.T, r *Report) {
+				assert.Greater(t, r.Successes, int64(10))
+				assert.Empty(t, r.Errors)
+				assert.GreaterOrEqual(t, r.Latency.P50, time.Millisecond)
+				assert.Greater(t, r.Throughput(), float64(0))
+			},
+		},
+		{
+			name: "Open loop",
+			args: func() (*config.Config, func(context.Context) error) {
+				return &config.Config{
+					LoadConcurrency: 2,
+					LoadRPS:         200,
+					LoadDuration:    100 * time.Millisecond,
+				}, func(context.Context) error {
+					return nil
+				}
+			},
+			check: func(t *testing.T, r *Report) {
+				assert.InDelta(t, 20, r.Successes, 5)
+				assert.Zero(t, r.Dropped)
+			},
+		},
+		{
+			name: "Errors by class",
+			args: func() (*config.Config, func(context.Context) error) {
+				var cnt atomic.Int64
+
+				return &config.Config{
+					LoadDuration: 20 * time.Millisecond,
+				}, func(context.Context) error {
+					time.Sleep(time.Millisecond)
+					if cnt.Add(1)%2 == 0 {
+						return errors.New("example error")
+					}
+
+					return nil
+				}
+			},
+			check: func(t *testing.T, r *Report) {
+				assert.Greater(t, r.Errors["example"], int64(0))
+				assert.InDelta(t, r.Successes, r.ErrorCount(), 1)
+			},
+		},
+		{
+			name: "Warm up excluded",
+			args: func() (*config.Config, func(context.Context) error) {
+				return &config.Config{
+					LoadDuration: 20 * time.Millisecond,
+					LoadWarmUp:   20 * time.Millisecond,
+				}, func(context.Context) error {
+					time.Sleep(time.Millisecond)
+
+					return nil
+				}
+			},
+			check: func(t *testing.T, r *Report) {
+				assert.Less(t, r.Successes, int64(25))
+				assert.InDelta(t, 20*time.Millisecond, r.Duration, float64(10*time.Millisecond))
+			},
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, request := tc.args()
+			ctx := context.Background()
+
+			report := New(ctx, cfg, request, func(error) string {
+				return "example"
+			}).Run(ctx)
+
+			tc.check(t, report)
+		})
+	}
+}
+
+func TestReport_Write(t *testing.T) {
+	report := &Report{
+		Duration:  time.Second,
+		Successes: 100,
+		Errors:    map[string]int64{"deadline": 2, "network": 1},
+		Latency: Latency{
+			P50: time.Millisecond,
+			Max: 10 * time.Millisecond,
+		},
+	}
+
+	t.Run("Text", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, report.Write(buf, ReportFormatText))
+
+		assert.Contains(t, buf.String(), "requests: 103 (errors: 3, dropped: 0)")
+		assert.Contains(t, buf.String(), "throughput: 100.00 rps")
+		assert.Contains(t, buf.String(), "errors: deadline=2 network=1")
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, report.Write(buf, ReportFormatJSON))
+
+		var result map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &result))
+		assert.Equal(t, float64(103), result["requests"])
+		assert.Equal(t, float64(1), result["latency_ms"].(map[string]interface{})["p50"])
+	})
+}
@@ -0,0 +1,238 @@
+// Package payload implements client request payload generators
+package payload
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+var ErrNoPayload = errors.New("no payload")
+
+const (
+	GeneratorRandom = "random"
+	GeneratorEdge   = "edge"
+	GeneratorFile   = "file"
+
+	SizeFixed   = "fixed"
+	SizeUniform = "uniform"
+
+	defaultSize     = 3
+	defaultMaxValue = 1024
+)
+
+// edgeValueList contains values close to integer overflow boundaries
+var edgeValueList = []int64{
+	math.MinInt64, math.MinInt64 + 1,
+	math.MinInt32 - 1, math.MinInt32,
+	-1, 0, 1,
+	math.MaxInt32, math.MaxInt32 + 1,
+	math.MaxInt64 - 1, math.MaxInt64,
+}
+
+// Generator returns next request payload, safe for concurrent use
+type Generator interface {
+	Next() ([]int64, error)
+}
+
+// New returns generator chosen by config, zero size and value range fall back to defaults,
+// generated payloads are never empty
+func New(ctx context.Context, config *config.Config) Generator {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.payload.New")
+	defer span.End()
+
+	if config.PayloadGenerator == GeneratorFile {
+		return &fileGenerator{path: config.PayloadFile}
+	}
+
+	g := &randomGenerator{
+		uniform:  config.PayloadSizeDistribution == SizeUniform,
+		minSize:  max(config.PayloadMinSize, 1),
+		maxSize:  config.PayloadSize,
+		minValue: config.PayloadMinValue,
+		maxValue: config.PayloadMaxValue,
+	}
+	if g.maxSize == 0 {
+		g.maxSize = defaultSize
+	}
+	if g.minValue == 0 && g.maxValue == 0 {
+		g.maxValue = defaultMaxValue
+	}
+	if config.PayloadGenerator == GeneratorEdge {
+		g.valueList = edgeValueList
+	}
+
+	seed := config.PayloadSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	g.rnd = rand.New(rand.NewSource(seed))
+
+	return g
+}
+
+// randomGenerator generates payloads of fixed or uniformly distributed size
+// with values from [minValue, maxValue) range or from value list if set
+type randomGenerator struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+
+	uniform          bool
+	minSize, maxSize int
+
+	minValue, maxValue int64
+	valueList          []int64
+}
+
+func (g *randomGenerator) Next() ([]int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	size := g.maxSize
+	if g.uniform && g.maxSize > g.minSize {
+		size = g.minSize + g.rnd.Intn(g.maxSize-g.minSize+1)
+	}
+
+	result := make([]int64, size)
+	for i := range result {
+		result[i] = g.value()
+	}
+
+	return result, nil
+}
+
+func (g *randomGenerator) value() int64 {
+	if len(g.valueList) > 0 {
+		return g.valueList[g.rnd.Intn(len(g.valueList))]
+	}
+
+	span := uint64(g.maxValue) - uint64(g.minValue)
+	if span == 0 {
+		return g.minValue
+	}
+
+	return g.minValue + int64(g.rnd.Uint64()%span)
+}
+
+// fileGenerator cycles through payloads read from JSON lines or CSV file
+type fileGenerator struct {
+	path string
+
+	loadOnce    sync.Once
+	loadErr     error
+	payloadList [][]int64
+
+	mu  sync.Mutex
+	pos int
+}
+
+func (g *fileGenerator) Next() ([]int64, error) {
+	g.loadOnce.Do(func() {
+		g.payloadList, g.loadErr = Load(g.path)
+	})
+	if g.loadErr != nil {
+		return nil, g.loadErr
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	payload := g.payloadList[g.pos]
+	g.pos = (g.pos + 1) % len(g.payloadList)
+
+	return append([]int64(nil), payload...), nil
+}
+
+// Load reads payloads from file, CSV format is chosen by .csv extension, JSON lines otherwise,
+// empty payloads are rejected
+func Load(path string) ([][]int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading payload file")
+	}
+
+	var payloadList [][]int64
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		payloadList, err = parseCSV(bytes.NewReader(data))
+	} else {
+		payloadList, err = parseJSONLines(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing payload file (%s)", path)
+	}
+	if len(payloadList) == 0 {
+		return nil, errors.Wrapf(ErrNoPayload, "payload file (%s)", path)
+	}
+	// empty rows are file mistakes, e.g. stray [] or null line
+	for i, payload := range payloadList {
+		if len(payload) == 0 {
+			return nil, errors.Wrapf(ErrNoPayload, "payload file (%s) row %d", path, i+1)
+		}
+	}
+
+	return payloadList, nil
+}
+
+func parseJSONLines(r io.Reader) ([][]int64, error) {
+	var result [][]int64
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var payload []int64
+		if err := json.Unmarshal([]byte(text), &payload); err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+		result = append(result, payload)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func parseCSV(r io.Reader) ([][]int64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var result [][]int64
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		payload := make([]int64, len(record))
+		for i, field := range record {
+			if payload[i], err = strconv.ParseInt(field, 10, 64); err != nil {
+				return nil, errors.Wrapf(err, "line %d", line)
+			}
+		}
+		result = append(result, payload)
+	}
+}
@@ -0,0 +1,222 @@
+package payload
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+)
+
+func TestNew(t *testing.T) {
+	testCaseList := []struct {
+		name  string
+		args  *config.Config
+		check func(t *testing.T, payload []int64)
+	}{
+		{
+			name: "Default",
+			args: &config.Config{},
+			check: func(t *testing.T, payload []int64) {
+				require.Len(t, payload, defaultSize)
+				for _, v := range payload {
+					assert.GreaterOrEqual(t, v, int64(0))
+					assert.Less(t, v, int64(defaultMaxValue))
+				}
+			},
+		},
+		{
+			name: "Uniform size with negative values",
+			args: &config.Config{
+				PayloadSizeDistribution: SizeUniform,
+				PayloadSize:             5,
+				PayloadMinSize:          2,
+				PayloadMinValue:         -10,
+				PayloadMaxValue:         -5,
+			},
+			check: func(t *testing.T, payload []int64) {
+				assert.GreaterOrEqual(t, len(payload), 2)
+				assert.LessOrEqual(t, len(payload), 5)
+				for _, v := range payload {
+					assert.GreaterOrEqual(t, v, int64(-10))
+					assert.Less(t, v, int64(-5))
+				}
+			},
+		},
+		{
+			name: "Uniform size without min size",
+			args: &config.Config{
+				PayloadSizeDistribution: SizeUniform,
+				PayloadSize:             2,
+			},
+			check: func(t *testing.T, payload []int64) {
+				assert.GreaterOrEqual(t, len(payload), 1)
+				assert.LessOrEqual(t, len(payload), 2)
+			},
+		},
+		{
+			name: "Full range",
+			args: &config.Config{
+				PayloadSize:     10,
+				PayloadMinValue: math.MinInt64,
+				PayloadMaxValue: math.MaxInt64,
+			},
+			check: func(t *testing.T, payload []int64) {
+				assert.Len(t, payload, 10)
+			},
+		},
+		{
+			name: "Edge values",
+			args: &config.Config{
+				PayloadGenerator: GeneratorEdge,
+				PayloadSize:      10,
+			},
+			check: func(t *testing.T, payload []int64) {
+				require.Len(t, payload, 10)
+				for _, v := range payload {
+					assert.Contains(t, edgeValueList, v)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			g := New(context.Background(), tc.args)
+
+			for i := 0; i < 100; i++ {
+				payload, err := g.Next()
+				require.NoError(t, err)
+				tc.check(t, payload)
+			}
+		})
+	}
+}
+
+func TestNew_seed(t *testing.T) {
+	cfg := &config.Config{
+		PayloadSizeDistribution: SizeUniform,
+		PayloadSize:             10,
+		PayloadSeed:             42,
+	}
+	g1, g2 := New(context.Background(), cfg), New(context.Background(), cfg)
+
+	for i := 0; i < 10; i++ {
+		p1, err := g1.Next()
+		require.NoError(t, err)
+		p2, err := g2.Next()
+		require.NoError(t, err)
+
+		assert.Equal(t, p1, p2)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func() (string, string)
+		wantResult [][]int64
+		wantError  bool
+	}{
+		{
+			name: "JSON lines",
+			args: func() (string, string) {
+				return "payload.jsonl", "[1, 2, 3]\n\n[-9223372036854775808, -1]\n"
+			},
+			wantResult: [][]int64{{1, 2, 3}, {math.MinInt64, -1}},
+		},
+		{
+			name: "CSV",
+			args: func() (string, string) {
+				return "payload.csv", "1,2,3\n9223372036854775807, 1\n"
+			},
+			wantResult: [][]int64{{1, 2, 3}, {math.MaxInt64, 1}},
+		},
+		{
+			name: "Invalid JSON line",
+			args: func() (string, string) {
+				return "payload.jsonl", "[1, 2, 3]\nexample\n"
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid CSV value",
+			args: func() (string, string) {
+				return "payload.csv", "1,example\n"
+			},
+			wantError: true,
+		},
+		{
+			name: "Empty JSON line payload",
+			args: func() (string, string) {
+				return "payload.jsonl", "[1, 2, 3]\n[]\n"
+			},
+			wantError: true,
+		},
+		{
+			name: "Null JSON line payload",
+			args: func() (string, string) {
+				return "payload.jsonl", "null\n"
+			},
+			wantError: true,
+		},
+		{
+			name: "Empty file",
+			args: func() (string, string) {
+				return "payload.jsonl", ""
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			name, data := tc.args()
+			path := filepath.Join(t.TempDir(), name)
+			require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+			result, err := Load(path)
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, result)
+		})
+	}
+}
+
+func TestFileGenerator_Next(t *testing.T) {
+	t.Run("Cycles through payloads", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "payload.csv")
+		require.NoError(t, os.WriteFile(path, []byte("1,2\n3\n"), 0o600))
+		g := New(context.Background(), &config.Config{
+			PayloadGenerator: GeneratorFile,
+			PayloadFile:      path,
+		})
+
+		for _, want := range [][]int64{{1, 2}, {3}, {1, 2}} {
+			payload, err := g.Next()
+			require.NoError(t, err)
+			assert.Equal(t, want, payload)
+		}
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		g := New(context.Background(), &config.Config{
+			PayloadGenerator: GeneratorFile,
+			PayloadFile:      filepath.Join(t.TempDir(), "example.jsonl"),
+		})
+
+		_, err := g.Next()
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package pool
+
+import "net"
+
+// healthy reports whether idle connection is still open and has no unexpected data
+func healthy(conn net.Conn) bool {
+	return readHealthy(conn)
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package pool
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// healthy reports whether idle connection is still open and has no unexpected data,
+// socket is peeked without waiting, so checkout is not delayed
+func healthy(conn net.Conn) bool {
+	sc, ok := netConn(conn).(syscall.Conn)
+	if !ok {
+		return readHealthy(conn)
+	}
+	rawConn, err := sc.SyscallConn()
+	if err != nil {
+		return false
+	}
+
+	var peekErr error
+	if err := rawConn.Read(func(fd uintptr) bool {
+		// socket is non-blocking, EAGAIN means nothing to read and peer didn't close connection
+		_, _, peekErr = syscall.Recvfrom(int(fd), make([]byte, 1), syscall.MSG_PEEK)
+
+		return true
+	}); err != nil {
+		return false
+	}
+
+	return errors.Is(peekErr, syscall.EAGAIN)
+}
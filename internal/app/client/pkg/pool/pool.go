@@ -0,0 +1,254 @@
+// Package pool implements client connections pool
+package pool
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
+	"github.com/kirill-a-belov/test_task_framework/pkg/metrics"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+const (
+	maintenanceInterval = 100 * time.Millisecond
+	healthCheckTimeout  = time.Millisecond
+)
+
+func New(ctx context.Context, config *config.Config, dialler func(context.Context) (net.Conn, error)) *Pool {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.pool.New")
+	defer span.End()
+
+	return &Pool{
+		config:   config,
+		dialler:  dialler,
+		stopChan: make(chan struct{}),
+		logger:   logger.New("client.pool"),
+
+		hitCounter:     metrics.NewCounter("client.pool.hit"),
+		missCounter:    metrics.NewCounter("client.pool.miss"),
+		evictedCounter: metrics.NewCounter("client.pool.evicted"),
+		idleGauge:      metrics.NewGauge("client.pool.idle"),
+
+		healthChecker: healthy,
+	}
+}
+
+type Pool struct {
+	config   *config.Config
+	dialler  func(context.Context) (net.Conn, error)
+	stopChan chan struct{}
+	logger   logger.Logger
+
+	mu       sync.Mutex
+	idleList []*Conn
+	stopped  bool
+
+	hitCounter     *metrics.Counter
+	missCounter    *metrics.Counter
+	evictedCounter *metrics.Counter
+	idleGauge      *metrics.Gauge
+
+	healthChecker func(net.Conn) bool
+}
+
+// Conn is a pooled connection
+type Conn struct {
+	net.Conn
+	createdAt time.Time
+}
+
+func (p *Pool) Start(ctx context.Context) {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.pool.Pool.Start")
+	defer span.End()
+
+	if p.config.PoolMaxIdle == 0 {
+		return
+	}
+
+	go p.processor(ctx)
+}
+
+func (p *Pool) processor(ctx context.Context) {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.pool.Pool.processor")
+	defer span.End()
+
+	ticker := time.NewTicker(maintenanceInterval)
+	defer ticker.Stop()
+
+	for {
+		p.evictExpired()
+		if err := p.fill(ctx); err != nil {
+			p.logger.Error(err, "filling pool")
+		}
+
+		select {
+		case <-p.stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Get returns idle healthy connection or dials a new one within context
+func (p *Pool) Get(ctx context.Context) (*Conn, error) {
+	for {
+		conn := p.pop()
+		if conn == nil {
+			break
+		}
+		if p.expired(conn) || !p.healthChecker(conn.Conn) {
+			p.evictedCounter.Inc()
+			_ = conn.Close()
+
+			continue
+		}
+		p.hitCounter.Inc()
+
+		return conn, nil
+	}
+
+	p.missCounter.Inc()
+
+	return p.dial(ctx)
+}
+
+// Put returns connection to pool or closes it when pool is full or stopped
+func (p *Pool) Put(conn *Conn) {
+	if p.expired(conn) {
+		p.evictedCounter.Inc()
+		_ = conn.Close()
+
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stopped || len(p.idleList) >= p.config.PoolMaxIdle {
+		_ = conn.Close()
+
+		return
+	}
+	p.idleList = append(p.idleList, conn)
+	p.idleGauge.Set(int64(len(p.idleList)))
+}
+
+// Discard closes broken connection
+func (p *Pool) Discard(conn *Conn) {
+	_ = conn.Close()
+}
+
+func (p *Pool) Stop(ctx context.Context) {
+	_, span := tracer.Start(ctx, "internal.app.client.pkg.pool.Pool.Stop")
+	defer span.End()
+
+	close(p.stopChan)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopped = true
+	for _, conn := range p.idleList {
+		_ = conn.Close()
+	}
+	p.idleList = nil
+	p.idleGauge.Set(0)
+}
+
+func (p *Pool) dial(ctx context.Context) (*Conn, error) {
+	conn, err := p.dialler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conn{
+		Conn:      conn,
+		createdAt: time.Now(),
+	}, nil
+}
+
+// pop takes most recently used connection
+func (p *Pool) pop() *Conn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.idleList) == 0 {
+		return nil
+	}
+	conn := p.idleList[len(p.idleList)-1]
+	p.idleList = p.idleList[:len(p.idleList)-1]
+	p.idleGauge.Set(int64(len(p.idleList)))
+
+	return conn
+}
+
+func (p *Pool) fill(ctx context.Context) error {
+	p.mu.Lock()
+	missingCnt := p.config.PoolMinIdle - len(p.idleList)
+	p.mu.Unlock()
+
+	for i := 0; i < missingCnt; i++ {
+		conn, err := p.dial(ctx)
+		if err != nil {
+			return errors.Wrap(err, "dialing idle connection")
+		}
+		p.Put(conn)
+	}
+
+	return nil
+}
+
+func (p *Pool) evictExpired() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	activeList := p.idleList[:0]
+	for _, conn := range p.idleList {
+		if p.expired(conn) {
+			p.evictedCounter.Inc()
+			_ = conn.Close()
+
+			continue
+		}
+		activeList = append(activeList, conn)
+	}
+	p.idleList = activeList
+	p.idleGauge.Set(int64(len(p.idleList)))
+}
+
+func (p *Pool) expired(conn *Conn) bool {
+	return p.config.PoolMaxLifetime > 0 && time.Since(conn.createdAt) > p.config.PoolMaxLifetime
+}
+
+// readHealthy reports whether idle connection is still open and has no unexpected data,
+// it blocks for health check timeout, so it is used only when socket can't be peeked
+func readHealthy(conn net.Conn) bool {
+	if err := conn.SetReadDeadline(time.Now().Add(healthCheckTimeout)); err != nil {
+		return false
+	}
+
+	_, err := conn.Read(make([]byte, 1))
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return false
+	}
+
+	return conn.SetReadDeadline(time.Time{}) == nil
+}
+
+// netConn unwraps connection wrappers, e.g. balanced endpoint connection
+func netConn(conn net.Conn) net.Conn {
+	for {
+		wrapper, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return conn
+		}
+		conn = wrapper.NetConn()
+	}
+}
@@ -0,0 +1,217 @@
+package pool
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+)
+
+func TestPool_Get(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      func() (*Pool, *Conn)
+		wantReuse bool
+		wantError bool
+	}{
+		{
+			name: "Reuse idle connection",
+			args: func() (*Pool, *Conn) {
+				p := New(context.Background(), &config.Config{PoolMaxIdle: 1}, pipeDialler)
+				p.healthChecker = func(net.Conn) bool { return true }
+				conn, err := p.Get(context.Background())
+				require.NoError(t, err)
+				p.Put(conn)
+
+				return p, conn
+			},
+			wantReuse: true,
+		},
+		{
+			name: "Unhealthy idle connection",
+			args: func() (*Pool, *Conn) {
+				p := New(context.Background(), &config.Config{PoolMaxIdle: 1}, pipeDialler)
+				p.healthChecker = func(net.Conn) bool { return false }
+				conn, err := p.Get(context.Background())
+				require.NoError(t, err)
+				p.Put(conn)
+
+				return p, conn
+			},
+			wantReuse: false,
+		},
+		{
+			name: "Expired idle connection",
+			args: func() (*Pool, *Conn) {
+				p := New(context.Background(), &config.Config{
+					PoolMaxIdle:     1,
+					PoolMaxLifetime: time.Millisecond,
+				}, pipeDialler)
+				p.healthChecker = func(net.Conn) bool { return true }
+				conn, err := p.Get(context.Background())
+				require.NoError(t, err)
+				p.Put(conn)
+				time.Sleep(2 * time.Millisecond)
+
+				return p, conn
+			},
+			wantReuse: false,
+		},
+		{
+			name: "Pool disabled",
+			args: func() (*Pool, *Conn) {
+				p := New(context.Background(), &config.Config{}, pipeDialler)
+				p.healthChecker = func(net.Conn) bool { return true }
+				conn, err := p.Get(context.Background())
+				require.NoError(t, err)
+				p.Put(conn)
+
+				return p, conn
+			},
+			wantReuse: false,
+		},
+		{
+			name: "Dialing error",
+			args: func() (*Pool, *Conn) {
+				p := New(context.Background(), &config.Config{PoolMaxIdle: 1}, func(context.Context) (net.Conn, error) {
+					return nil, errors.New("example error")
+				})
+
+				return p, nil
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			p, prevConn := tc.args()
+			defer p.Stop(context.Background())
+
+			conn, err := p.Get(context.Background())
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantReuse, conn == prevConn)
+		})
+	}
+}
+
+func TestPool_Start(t *testing.T) {
+	t.Run("Fill min idle", func(t *testing.T) {
+		ctx := context.Background()
+		p := New(ctx, &config.Config{
+			PoolMinIdle: 2,
+			PoolMaxIdle: 3,
+		}, pipeDialler)
+		p.Start(ctx)
+		defer p.Stop(ctx)
+
+		assert.Eventually(t, func() bool {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+
+			return len(p.idleList) == 2
+		}, time.Second, time.Millisecond)
+	})
+}
+
+func TestPool_Put(t *testing.T) {
+	t.Run("Put after stop", func(t *testing.T) {
+		ctx := context.Background()
+		p := New(ctx, &config.Config{PoolMaxIdle: 1}, pipeDialler)
+		conn, err := p.Get(ctx)
+		require.NoError(t, err)
+		p.Stop(ctx)
+
+		p.Put(conn)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		assert.Empty(t, p.idleList)
+		assert.ErrorIs(t, conn.SetDeadline(time.Now()), io.ErrClosedPipe)
+	})
+}
+
+func Test_healthy(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func(server net.Conn)
+		wantResult bool
+	}{
+		{
+			name:       "Open connection",
+			args:       func(net.Conn) {},
+			wantResult: true,
+		},
+		{
+			name: "Closed by peer",
+			args: func(server net.Conn) {
+				_ = server.Close()
+			},
+			wantResult: false,
+		},
+		{
+			name: "Unexpected data",
+			args: func(server net.Conn) {
+				_, _ = server.Write([]byte("example"))
+			},
+			wantResult: false,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			listener, err := net.Listen(protocol.NetworkType, "127.0.0.1:0")
+			require.NoError(t, err)
+			defer func() {
+				_ = listener.Close()
+			}()
+
+			client, err := net.Dial(protocol.NetworkType, listener.Addr().String())
+			require.NoError(t, err)
+			defer func() {
+				_ = client.Close()
+			}()
+			server, err := listener.Accept()
+			require.NoError(t, err)
+			defer func() {
+				_ = server.Close()
+			}()
+
+			tc.args(server)
+			time.Sleep(10 * time.Millisecond)
+
+			assert.Equal(t, tc.wantResult, healthy(client), "peeked")
+			assert.Equal(t, tc.wantResult, healthy(wrappedConn{Conn: client}), "wrapped")
+			// last, as it consumes unexpected data
+			assert.Equal(t, tc.wantResult, readHealthy(client), "read")
+		})
+	}
+}
+
+type wrappedConn struct {
+	net.Conn
+}
+
+func (c wrappedConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+func pipeDialler(context.Context) (net.Conn, error) {
+	conn, _ := net.Pipe()
+
+	return conn, nil
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"github.com/kirill-a-belov/test_task_framework/pkg/math"
+)
+
+// Expectation calculates operation result locally for verification
+type Expectation func(args []int64) int64
+
+// RegisterExpectation makes operation results verified against expectation when verification is enabled
+func (c *Client) RegisterExpectation(name string, expect Expectation) {
+	c.operationMu.Lock()
+	defer c.operationMu.Unlock()
+
+	c.expectationList[name] = expect
+}
+
+// verify compares received result with locally expected one, operations without expectation are not verified
+func (c *Client) verify(name string, args []int64, result int64) error {
+	if !c.config.Verify {
+		return nil
+	}
+
+	c.operationMu.RLock()
+	expect, ok := c.expectationList[name]
+	c.operationMu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	c.verifiedCounter.Inc()
+	expected := expect(append([]int64(nil), args...))
+	if expected == result {
+		return nil
+	}
+
+	c.mismatchCounter.Inc()
+	err := &MismatchError{
+		Operation: name,
+		Args:      append([]int64(nil), args...),
+		Expected:  expected,
+		Received:  result,
+	}
+	c.logger.Error(err, "response verification",
+		"operation", name,
+		"args", err.Args,
+		"expected", expected,
+		"received", result,
+	)
+
+	return err
+}
+
+func expectSum(args []int64) int64 {
+	if len(args) == 0 {
+		return 0
+	}
+
+	return math.Sum(args...)
+}
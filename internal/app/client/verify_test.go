@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/client/pkg/config"
+)
+
+func TestClient_verify(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func() (*config.Config, string, int64)
+		wantError  bool
+		wantChecks int64
+	}{
+		{
+			name: "Match",
+			args: func() (*config.Config, string, int64) {
+				return &config.Config{Verify: true}, OperationSum, 6
+			},
+			wantChecks: 1,
+		},
+		{
+			name: "Mismatch",
+			args: func() (*config.Config, string, int64) {
+				return &config.Config{Verify: true}, OperationSum, 7
+			},
+			wantError:  true,
+			wantChecks: 1,
+		},
+		{
+			name: "Disabled",
+			args: func() (*config.Config, string, int64) {
+				return &config.Config{}, OperationSum, 7
+			},
+		},
+		{
+			name: "No expectation",
+			args: func() (*config.Config, string, int64) {
+				return &config.Config{Verify: true}, "example", 7
+			},
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, name, result := tc.args()
+			c := New(context.Background(), cfg)
+			checked, mismatched := c.verifiedCounter.Value(), c.mismatchCounter.Value()
+			args := []int64{1, 2, 3}
+
+			err := c.verify(name, args, result)
+
+			assert.Equal(t, []int64{1, 2, 3}, args)
+			assert.Equal(t, checked+tc.wantChecks, c.verifiedCounter.Value())
+			if tc.wantError {
+				var mismatchErr *MismatchError
+				require.ErrorAs(t, err, &mismatchErr)
+				assert.Equal(t, int64(6), mismatchErr.Expected)
+				assert.Equal(t, result, mismatchErr.Received)
+				assert.Equal(t, args, mismatchErr.Args)
+				assert.Equal(t, mismatched+1, c.mismatchCounter.Value())
+				assert.Equal(t, "mismatch", ErrorClass(err))
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, mismatched, c.mismatchCounter.Value())
+		})
+	}
+}
+
+func TestClient_RegisterExpectation(t *testing.T) {
+	c := New(context.Background(), &config.Config{Verify: true})
+	c.RegisterExpectation("example", func(args []int64) int64 {
+		return args[0]
+	})
+
+	assert.NoError(t, c.verify("example", []int64{5, 2}, 5))
+	assert.Error(t, c.verify("example", []int64{5, 2}, 7))
+}
@@ -2,6 +2,9 @@ package client
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -29,6 +32,21 @@ func New(ctx context.Context) cobra.Command {
 				return errors.Wrap(err, "loading config")
 			}
 
+			if cfg.Mode == config.ModeLoad {
+				ctx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+				defer cancel()
+
+				report, err := client.New(ctx, cfg).RunLoad(ctx)
+				if err != nil {
+					return errors.Wrap(err, "running load")
+				}
+				if err := report.Write(os.Stdout, cfg.LoadReportFormat); err != nil {
+					return errors.Wrap(err, "writing load report")
+				}
+
+				return nil
+			}
+
 			runner.New(client.New(ctx, cfg), logger.New("client")).Run(ctx)
 
 			return nil
@@ -2,22 +2,33 @@ package kafka
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka"
 	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/dump"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/factory"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/lag"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/provision"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/retry"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/topic"
 	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
 	"github.com/kirill-a-belov/test_task_framework/pkg/runner"
 	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
 )
 
-func New(ctx context.Context) cobra.Command {
+func New(ctx context.Context) *cobra.Command {
 	ctx, span := tracer.Start(ctx, "internal.app.cmd.kafka.New")
 	defer span.End()
 
-	return cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "kafka",
 		Short: "Kafka services set",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -34,4 +45,349 @@ func New(ctx context.Context) cobra.Command {
 			return nil
 		},
 	}
+	cmd.AddCommand(newRedrive(ctx), newProvision(ctx), newLag(ctx), newDump(ctx), newReplay(ctx))
+
+	return cmd
+}
+
+func newRedrive(ctx context.Context) *cobra.Command {
+	var (
+		topic string
+		limit int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "redrive",
+		Short: "Move dead letter messages back to their original topics",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, span := tracer.Start(ctx, "internal.app.cmd.kafka.newRedrive.Run")
+			defer span.End()
+
+			cfg := &config.Config{}
+			if err := cfg.Load(ctx); err != nil {
+				return errors.Wrap(err, "loading config")
+			}
+			if topic == "" {
+				topic = retry.DeadLetterTopic(cfg.RequestTopic)
+			}
+
+			f := factory.Default()
+			saramaConfig, err := cfg.SaramaConfig()
+			if err != nil {
+				return errors.Wrap(err, "creating sarama config")
+			}
+			client, err := f.Client([]string{cfg.Address}, saramaConfig)
+			if err != nil {
+				return errors.Wrap(err, "creating client")
+			}
+			defer func() {
+				_ = client.Close()
+			}()
+			producer, err := f.SyncProducer(client)
+			if err != nil {
+				return errors.Wrap(err, "creating producer")
+			}
+			defer func() {
+				_ = producer.Close()
+			}()
+
+			redrivenCnt, err := retry.Redrive(ctx, client, producer, topic, cfg.GroupID+".redrive", limit)
+			fmt.Fprintf(cmd.OutOrStdout(), "redriven %d messages from %s\n", redrivenCnt, topic)
+			if err != nil {
+				return errors.Wrap(err, "redriving dead letter topic")
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&topic, "topic", "", "dead letter topic, request topic one by default")
+	cmd.Flags().IntVar(&limit, "limit", 0, "maximum number of messages, 0 means no limit")
+
+	return cmd
+}
+
+func newProvision(ctx context.Context) *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "provision",
+		Short: "Create missing topics and report drift of existing ones",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, span := tracer.Start(ctx, "internal.app.cmd.kafka.newProvision.Run")
+			defer span.End()
+
+			cfg := &config.Config{}
+			if err := cfg.Load(ctx); err != nil {
+				return errors.Wrap(err, "loading config")
+			}
+			if !cmd.Flags().Changed("dry-run") {
+				dryRun = cfg.TopicProvisionDryRun
+			}
+
+			specList, err := provision.Specs(cfg)
+			if err != nil {
+				return err
+			}
+			f := factory.Default()
+			saramaConfig, err := cfg.SaramaConfig()
+			if err != nil {
+				return errors.Wrap(err, "creating sarama config")
+			}
+			client, err := f.Client([]string{cfg.Address}, saramaConfig)
+			if err != nil {
+				return errors.Wrap(err, "creating client")
+			}
+			defer func() {
+				_ = client.Close()
+			}()
+			admin, err := f.ClusterAdmin(client)
+			if err != nil {
+				return errors.Wrap(err, "creating cluster admin")
+			}
+			defer func() {
+				_ = admin.Close()
+			}()
+
+			report, err := provision.Reconcile(ctx, admin, specList, dryRun)
+			if report != nil {
+				action := "created"
+				if dryRun {
+					action = "would create"
+				}
+				for _, topic := range report.CreatedList {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", action, topic)
+				}
+				for _, drift := range report.DriftList {
+					fmt.Fprintf(cmd.OutOrStdout(), "drift %s\n", drift)
+				}
+			}
+			if err != nil {
+				return errors.Wrap(err, "reconciling topics")
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "validate topic creation without creating topics, KAFKA_TOPIC_PROVISION_DRY_RUN by default")
+
+	return cmd
+}
+
+func newLag(ctx context.Context) *cobra.Command {
+	var (
+		groupID   string
+		topicList []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lag",
+		Short: "Print consumer group lag per topic partition",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, span := tracer.Start(ctx, "internal.app.cmd.kafka.newLag.Run")
+			defer span.End()
+
+			cfg := &config.Config{}
+			if err := cfg.Load(ctx); err != nil {
+				return errors.Wrap(err, "loading config")
+			}
+			if groupID == "" {
+				groupID = cfg.GroupID
+			}
+			if len(topicList) == 0 {
+				topicList = []string{cfg.RequestTopic}
+				for i := range cfg.RetryTopicDelayList {
+					topicList = append(topicList, retry.Topic(cfg.RequestTopic, i+1))
+				}
+			}
+
+			f := factory.Default()
+			saramaConfig, err := cfg.SaramaConfig()
+			if err != nil {
+				return errors.Wrap(err, "creating sarama config")
+			}
+			client, err := f.Client([]string{cfg.Address}, saramaConfig)
+			if err != nil {
+				return errors.Wrap(err, "creating client")
+			}
+			defer func() {
+				_ = client.Close()
+			}()
+			admin, err := f.ClusterAdmin(client)
+			if err != nil {
+				return errors.Wrap(err, "creating cluster admin")
+			}
+
+			partitionList, err := lag.Measure(ctx, client, admin, groupID, topicList)
+			if err != nil {
+				return errors.Wrap(err, "measuring lag")
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "GROUP\tTOPIC\tPARTITION\tHIGH WATER MARK\tOFFSET\tLAG")
+			for _, p := range partitionList {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\n", p.Group, p.Topic, p.Partition, p.HighWaterMark, p.Offset, p.Lag)
+			}
+
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&groupID, "group", "", "consumer group, configured group by default")
+	cmd.Flags().StringSliceVar(&topicList, "topic", nil, "topics, request topic and its retry topics by default")
+
+	return cmd
+}
+
+func newDump(ctx context.Context) *cobra.Command {
+	var (
+		name     string
+		output   string
+		r        dump.Range
+		fromTime string
+		toTime   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "dump",
+		Short: "Write topic messages to JSON lines file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, span := tracer.Start(ctx, "internal.app.cmd.kafka.newDump.Run")
+			defer span.End()
+
+			cfg := &config.Config{}
+			if err := cfg.Load(ctx); err != nil {
+				return errors.Wrap(err, "loading config")
+			}
+			if name == "" {
+				name = cfg.RequestTopic
+			}
+			var err error
+			if fromTime != "" {
+				if r.FromTime, err = time.Parse(time.RFC3339, fromTime); err != nil {
+					return errors.Wrap(err, "parsing start time")
+				}
+			}
+			if toTime != "" {
+				if r.ToTime, err = time.Parse(time.RFC3339, toTime); err != nil {
+					return errors.Wrap(err, "parsing end time")
+				}
+			}
+
+			topicSpecList, err := cfg.TopicSpecs()
+			if err != nil {
+				return errors.Wrap(err, "parsing topic list")
+			}
+			registry, err := topic.New(topicSpecList)
+			if err != nil {
+				return errors.Wrap(err, "creating topic registry")
+			}
+
+			f := factory.Default()
+			saramaConfig, err := cfg.SaramaConfig()
+			if err != nil {
+				return errors.Wrap(err, "creating sarama config")
+			}
+			client, err := f.Client([]string{cfg.Address}, saramaConfig)
+			if err != nil {
+				return errors.Wrap(err, "creating client")
+			}
+			defer func() {
+				_ = client.Close()
+			}()
+
+			w := cmd.OutOrStdout()
+			if output != "" {
+				f, err := os.Create(output)
+				if err != nil {
+					return errors.Wrap(err, "creating output file")
+				}
+				defer func() {
+					_ = f.Close()
+				}()
+				w = f
+			}
+
+			dumpedCnt, err := dump.Dump(ctx, client, registry, w, name, r)
+			fmt.Fprintf(cmd.ErrOrStderr(), "dumped %d messages from %s\n", dumpedCnt, name)
+			if err != nil {
+				return errors.Wrap(err, "dumping topic")
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "topic", "", "topic, request topic by default")
+	cmd.Flags().StringVar(&output, "output", "", "output file, stdout by default")
+	cmd.Flags().Int64Var(&r.FromOffset, "from-offset", 0, "first offset of every partition")
+	cmd.Flags().Int64Var(&r.ToOffset, "to-offset", 0, "offset after the last one of every partition, 0 means partition end")
+	cmd.Flags().StringVar(&fromTime, "from-time", "", "RFC3339 time of the first message")
+	cmd.Flags().StringVar(&toTime, "to-time", "", "RFC3339 time messages are dumped until")
+
+	return cmd
+}
+
+func newReplay(ctx context.Context) *cobra.Command {
+	var (
+		input string
+		name  string
+		rate  float64
+	)
+
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Produce messages of JSON lines file written by dump",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, span := tracer.Start(ctx, "internal.app.cmd.kafka.newReplay.Run")
+			defer span.End()
+
+			cfg := &config.Config{}
+			if err := cfg.Load(ctx); err != nil {
+				return errors.Wrap(err, "loading config")
+			}
+
+			var r io.Reader = cmd.InOrStdin()
+			if input != "" {
+				f, err := os.Open(input)
+				if err != nil {
+					return errors.Wrap(err, "opening input file")
+				}
+				defer func() {
+					_ = f.Close()
+				}()
+				r = f
+			}
+
+			f := factory.Default()
+			saramaConfig, err := cfg.SaramaConfig()
+			if err != nil {
+				return errors.Wrap(err, "creating sarama config")
+			}
+			client, err := f.Client([]string{cfg.Address}, saramaConfig)
+			if err != nil {
+				return errors.Wrap(err, "creating client")
+			}
+			defer func() {
+				_ = client.Close()
+			}()
+			producer, err := f.SyncProducer(client)
+			if err != nil {
+				return errors.Wrap(err, "creating producer")
+			}
+			defer func() {
+				_ = producer.Close()
+			}()
+
+			replayedCnt, err := dump.Replay(ctx, r, producer, name, rate)
+			fmt.Fprintf(cmd.OutOrStdout(), "replayed %d messages\n", replayedCnt)
+			if err != nil {
+				return errors.Wrap(err, "replaying messages")
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&input, "input", "", "input file, stdin by default")
+	cmd.Flags().StringVar(&name, "topic", "", "target topic, topics of dumped messages by default")
+	cmd.Flags().Float64Var(&rate, "rate", 0, "maximum messages per second, 0 means no limit")
+
+	return cmd
 }
@@ -0,0 +1,252 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/header"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/retry"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/pkg/backoff"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+// consume joins consumer group until context is cancelled, session is restarted after every rebalance
+func (k *Kafka) consume(ctx context.Context, topicList []string) {
+	_, span := tracer.Start(ctx, "internal.app.kafka.Kafka.consume")
+	defer span.End()
+
+	retryPolicy := k.config.RetryPolicy()
+	failedCnt := 0
+	for {
+		if err := k.consumerGroup.Consume(ctx, topicList, &groupHandler{kafka: k}); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				return
+			}
+			failedCnt++
+			k.logger.Error(err, "consumer group session", "attempt", failedCnt)
+			if err := backoff.Wait(ctx, retryPolicy.Delay(failedCnt)); err != nil {
+				return
+			}
+
+			continue
+		}
+		failedCnt = 0
+
+		if ctx.Err() != nil {
+			k.logger.Info("consumer terminated")
+
+			return
+		}
+	}
+}
+
+// groupHandler processes partitions claimed by consumer group member
+type groupHandler struct {
+	kafka *Kafka
+}
+
+func (h *groupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	if err := h.kafka.position(session); err != nil {
+		return errors.Wrap(err, "positioning claimed partitions")
+	}
+
+	claimCnt := 0
+	for _, partitionList := range session.Claims() {
+		claimCnt += len(partitionList)
+	}
+	h.kafka.partitionGauge.Set(int64(claimCnt))
+	h.kafka.logger.Info("partitions assigned",
+		"member", session.MemberID(),
+		"generation", session.GenerationID(),
+		"claims", session.Claims(),
+	)
+
+	return nil
+}
+
+func (h *groupHandler) Cleanup(session sarama.ConsumerGroupSession) error {
+	if h.kafka.config.OffsetCommit == config.OffsetCommitManual {
+		session.Commit()
+	}
+	h.kafka.forgetProcessed(session.Claims())
+	h.kafka.partitionGauge.Set(0)
+	h.kafka.logger.Info("partitions revoked",
+		"member", session.MemberID(),
+		"generation", session.GenerationID(),
+	)
+
+	return nil
+}
+
+func (h *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+
+			if h.kafka.retry != nil {
+				if err := h.kafka.retry.Wait(session.Context(), msg); err != nil {
+					return nil
+				}
+			}
+
+			h.kafka.consumedCounter.Inc()
+			if err := h.kafka.transact(msg, func() error {
+				return h.kafka.handle(session.Context(), msg)
+			}); err != nil {
+				// message stays unmarked to be redelivered
+				return errors.Wrap(err, "handling message")
+			}
+			session.MarkMessage(msg, "")
+			h.kafka.markProcessed(msg)
+			if h.kafka.config.OffsetCommit == config.OffsetCommitManual && len(claim.Messages()) == 0 {
+				session.Commit()
+			}
+		case <-session.Context().Done():
+			return nil
+		}
+	}
+}
+
+// handle processes message and routes it to retry pipeline on failure, routing waits for delivery,
+// so only its error is returned to leave message unacknowledged
+func (k *Kafka) handle(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	err := k.receive(ctx, msg)
+	if err == nil {
+		return nil
+	}
+
+	k.failedCounter.Inc()
+	k.logger.Error(err, "message processing",
+		"topic", msg.Topic,
+		"partition", msg.Partition,
+		"offset", msg.Offset,
+	)
+	if k.retry == nil {
+		return nil
+	}
+	if err := k.retry.Fail(msg, err); err != nil {
+		return errors.Wrap(err, "routing failed message")
+	}
+
+	return nil
+}
+
+// position moves claimed partitions without committed offset to configured initial position,
+// in reset mode partitions are moved once per start regardless of committed offset
+func (k *Kafka) position(session sarama.ConsumerGroupSession) error {
+	if !k.config.OffsetReset &&
+		k.config.OffsetInitial != config.OffsetInitialOffset &&
+		k.config.OffsetInitial != config.OffsetInitialTimestamp {
+		return nil
+	}
+
+	committed, err := k.admin.ListConsumerGroupOffsets(k.config.GroupID, session.Claims())
+	if err != nil {
+		return errors.Wrap(err, "fetching committed offsets")
+	}
+
+	for topic, partitionList := range session.Claims() {
+		for _, partition := range partitionList {
+			reset := k.config.OffsetReset && k.markReset(topic, partition)
+			if block := committed.GetBlock(topic, partition); !reset && block != nil && block.Offset >= 0 {
+				continue
+			}
+
+			offset, err := k.initialOffset(topic, partition)
+			if err != nil {
+				return errors.Wrapf(err, "resolving initial offset (%s/%d)", topic, partition)
+			}
+			// only one of them takes effect: mark moves offset forward, reset moves it back
+			session.MarkOffset(topic, partition, offset, "")
+			session.ResetOffset(topic, partition, offset, "")
+			k.logger.Info("partition positioned",
+				"topic", topic,
+				"partition", partition,
+				"offset", offset,
+				"reset", reset,
+			)
+		}
+	}
+
+	return nil
+}
+
+// initialOffset resolves configured initial position to partition offset
+func (k *Kafka) initialOffset(topic string, partition int32) (int64, error) {
+	switch k.config.OffsetInitial {
+	case config.OffsetInitialOldest:
+		return k.client.GetOffset(topic, partition, sarama.OffsetOldest)
+	case config.OffsetInitialOffset:
+		return k.config.OffsetInitialOffset, nil
+	case config.OffsetInitialTimestamp:
+		timestamp, err := k.config.InitialTimestamp()
+		if err != nil {
+			return 0, errors.Wrap(err, "parsing initial timestamp")
+		}
+		offset, err := k.client.GetOffset(topic, partition, timestamp.UnixMilli())
+		if err != nil || offset >= 0 {
+			return offset, err
+		}
+		// no message produced after timestamp
+		return k.client.GetOffset(topic, partition, sarama.OffsetNewest)
+	default:
+		return k.client.GetOffset(topic, partition, sarama.OffsetNewest)
+	}
+}
+
+// markReset returns true only for the first reset of partition
+func (k *Kafka) markReset(topic string, partition int32) bool {
+	k.resetMu.Lock()
+	defer k.resetMu.Unlock()
+
+	key := fmt.Sprintf("%s/%d", topic, partition)
+	if _, ok := k.resetDoneList[key]; ok {
+		return false
+	}
+	k.resetDoneList[key] = struct{}{}
+
+	return true
+}
+
+// receive decodes message into type declared by its headers or bound to its original topic
+// and dispatches it to handler of this type, trace started by producer is continued
+func (k *Kafka) receive(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	ctx, span := tracer.Start(header.Context(ctx, msg.Headers), "internal.app.kafka.Kafka.receive")
+	defer span.End()
+
+	if version := header.Get(msg.Headers, header.SchemaVersion); version != "" &&
+		version != strconv.Itoa(protocol.SchemaVersion) {
+		return errors.Errorf("unsupported schema version (%s)", version)
+	}
+
+	originalTopic := retry.OriginalTopic(msg)
+	t, err := k.registry.Topic(originalTopic)
+	if err != nil {
+		return err
+	}
+	messageType := header.Get(msg.Headers, header.MessageType)
+	if messageType == "" {
+		// message of producer not sending type header
+		messageType = t.Message
+	}
+	value, err := k.registry.DecodeMessage(originalTopic, messageType, header.Get(msg.Headers, header.ContentType), msg.Value)
+	if err != nil {
+		return err
+	}
+
+	handler, ok := k.handlerList[messageType]
+	if !ok {
+		return errors.Errorf("no handler of message type (%s)", messageType)
+	}
+
+	return handler(ctx, msg, value)
+}
@@ -0,0 +1,453 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/header"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/retry"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/topic"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+const (
+	topicA = "example_topic_a"
+	topicB = "example_topic_B"
+)
+
+func newRegistry(t *testing.T) *topic.Registry {
+	registry, err := topic.New([]config.TopicSpec{
+		{Name: topicA, Message: topic.MessageRequest, Codec: config.CodecGob},
+		{Name: topicB, Message: topic.MessageResponse, Codec: config.CodecJSON},
+	})
+	require.NoError(t, err)
+
+	return registry
+}
+
+type sessionStub struct {
+	sarama.ConsumerGroupSession
+	ctx    context.Context
+	claims map[string][]int32
+
+	mu         sync.Mutex
+	markedList []*sarama.ConsumerMessage
+	offsetList map[string]int64
+	commitCnt  int
+}
+
+func (s *sessionStub) Context() context.Context   { return s.ctx }
+func (s *sessionStub) Claims() map[string][]int32 { return s.claims }
+func (s *sessionStub) MemberID() string           { return "member" }
+func (s *sessionStub) GenerationID() int32        { return 1 }
+func (s *sessionStub) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.markedList = append(s.markedList, msg)
+}
+
+func (s *sessionStub) MarkOffset(topic string, partition int32, offset int64, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.offsetList == nil {
+		s.offsetList = make(map[string]int64)
+	}
+	if key := fmt.Sprintf("%s/%d", topic, partition); offset > s.offsetList[key] {
+		s.offsetList[key] = offset
+	}
+}
+
+func (s *sessionStub) ResetOffset(topic string, partition int32, offset int64, _ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.offsetList == nil {
+		s.offsetList = make(map[string]int64)
+	}
+	if key := fmt.Sprintf("%s/%d", topic, partition); offset < s.offsetList[key] {
+		s.offsetList[key] = offset
+	}
+}
+
+func (s *sessionStub) Commit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commitCnt++
+}
+
+type claimStub struct {
+	sarama.ConsumerGroupClaim
+	msgChan chan *sarama.ConsumerMessage
+}
+
+func (c *claimStub) Messages() <-chan *sarama.ConsumerMessage { return c.msgChan }
+
+type adminStub struct {
+	sarama.ClusterAdmin
+	committed map[string]map[int32]int64
+}
+
+func (a *adminStub) ListConsumerGroupOffsets(
+	string,
+	map[string][]int32,
+) (*sarama.OffsetFetchResponse, error) {
+	response := &sarama.OffsetFetchResponse{}
+	for topic, partitionList := range a.committed {
+		for partition, offset := range partitionList {
+			response.AddBlock(topic, partition, &sarama.OffsetFetchResponseBlock{Offset: offset})
+		}
+	}
+
+	return response, nil
+}
+
+type clientStub struct {
+	sarama.Client
+}
+
+func (*clientStub) GetOffset(_ string, _ int32, time int64) (int64, error) {
+	switch time {
+	case sarama.OffsetOldest:
+		return 10, nil
+	case sarama.OffsetNewest:
+		return 100, nil
+	default:
+		return -1, nil
+	}
+}
+
+func TestGroupHandler_Setup(t *testing.T) {
+	k := New(context.Background(), &config.Config{})
+	h := &groupHandler{kafka: k}
+	session := &sessionStub{claims: map[string][]int32{
+		topicA: {0, 1, 2},
+		topicB: {0},
+	}}
+
+	require.NoError(t, h.Setup(session))
+	assert.Equal(t, int64(4), k.partitionGauge.Value())
+	k.markProcessed(&sarama.ConsumerMessage{Topic: topicA, Partition: 1, Offset: 5})
+
+	require.NoError(t, h.Cleanup(session))
+	assert.Equal(t, int64(0), k.partitionGauge.Value())
+	_, ok := k.processed(topicA, 1)
+	assert.False(t, ok)
+}
+
+func TestGroupHandler_ConsumeClaim(t *testing.T) {
+	t.Run("Marks processed and failed messages", func(t *testing.T) {
+		value := &bytes.Buffer{}
+		require.NoError(t, gob.NewEncoder(value).Encode(protocol.Request{
+			Message: protocol.Message{
+				Type: protocol.MessageTypeRequest,
+			},
+			Payload: []int64{1, 2, 3},
+		}))
+
+		k := New(context.Background(), &config.Config{ReplyTopic: topicB})
+		k.registry = newRegistry(t)
+		producer := mocks.NewSyncProducer(t, nil)
+		producer.ExpectSendMessageAndSucceed()
+		k.producer = producer
+		consumed, failed := k.consumedCounter.Value(), k.failedCounter.Value()
+		session := &sessionStub{ctx: context.Background()}
+		claim := &claimStub{msgChan: make(chan *sarama.ConsumerMessage, 2)}
+		claim.msgChan <- &sarama.ConsumerMessage{Topic: topicA, Value: value.Bytes()}
+		claim.msgChan <- &sarama.ConsumerMessage{Topic: topicA, Value: []byte("example")}
+		close(claim.msgChan)
+
+		require.NoError(t, (&groupHandler{kafka: k}).ConsumeClaim(session, claim))
+
+		assert.Len(t, session.markedList, 2)
+		assert.Equal(t, consumed+2, k.consumedCounter.Value())
+		assert.Equal(t, failed+1, k.failedCounter.Value())
+		assert.NoError(t, producer.Close())
+	})
+
+	t.Run("Commits manually after processing", func(t *testing.T) {
+		k := New(context.Background(), &config.Config{OffsetCommit: config.OffsetCommitManual})
+		k.registry = newRegistry(t)
+		session := &sessionStub{ctx: context.Background()}
+		claim := &claimStub{msgChan: make(chan *sarama.ConsumerMessage, 2)}
+		claim.msgChan <- &sarama.ConsumerMessage{Topic: topicA}
+		claim.msgChan <- &sarama.ConsumerMessage{Topic: topicA}
+		close(claim.msgChan)
+
+		require.NoError(t, (&groupHandler{kafka: k}).ConsumeClaim(session, claim))
+		require.NoError(t, (&groupHandler{kafka: k}).Cleanup(session))
+
+		assert.Len(t, session.markedList, 2)
+		assert.Equal(t, 2, session.commitCnt)
+	})
+
+	t.Run("Routes failed messages to retry topic", func(t *testing.T) {
+		cfg := &config.Config{RetryTopicDelayList: []time.Duration{time.Second}}
+		k := New(context.Background(), cfg)
+		k.registry = newRegistry(t)
+		producer := mocks.NewSyncProducer(t, nil)
+		producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+			if msg.Topic != retry.Topic(topicA, 1) {
+				return fmt.Errorf("unexpected topic (%s)", msg.Topic)
+			}
+
+			return nil
+		})
+		k.retry = retry.New(context.Background(), cfg, producer)
+		session := &sessionStub{ctx: context.Background()}
+		claim := &claimStub{msgChan: make(chan *sarama.ConsumerMessage, 1)}
+		claim.msgChan <- &sarama.ConsumerMessage{Topic: topicA, Value: []byte("example")}
+		close(claim.msgChan)
+
+		require.NoError(t, (&groupHandler{kafka: k}).ConsumeClaim(session, claim))
+
+		assert.Len(t, session.markedList, 1)
+		assert.NoError(t, producer.Close())
+	})
+
+	t.Run("Keeps message unmarked when routing fails", func(t *testing.T) {
+		cfg := &config.Config{DeadLetter: true}
+		k := New(context.Background(), cfg)
+		k.registry = newRegistry(t)
+		producer := mocks.NewSyncProducer(t, nil)
+		producer.ExpectSendMessageAndFail(assert.AnError)
+		k.retry = retry.New(context.Background(), cfg, producer)
+		session := &sessionStub{ctx: context.Background()}
+		claim := &claimStub{msgChan: make(chan *sarama.ConsumerMessage, 1)}
+		claim.msgChan <- &sarama.ConsumerMessage{Topic: topicA, Value: []byte("example")}
+
+		assert.ErrorIs(t, (&groupHandler{kafka: k}).ConsumeClaim(session, claim), assert.AnError)
+		assert.Empty(t, session.markedList)
+		assert.NoError(t, producer.Close())
+	})
+
+	t.Run("Stops with session", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		session := &sessionStub{ctx: ctx}
+		claim := &claimStub{msgChan: make(chan *sarama.ConsumerMessage)}
+
+		assert.NoError(t, (&groupHandler{kafka: New(ctx, &config.Config{})}).ConsumeClaim(session, claim))
+	})
+}
+
+func TestKafka_position(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       *config.Config
+		wantResult map[string]int64
+	}{
+		{
+			name:       "Native initial position",
+			args:       &config.Config{OffsetInitial: config.OffsetInitialOldest},
+			wantResult: nil,
+		},
+		{
+			name: "Specific offset without committed one",
+			args: &config.Config{
+				OffsetInitial:       config.OffsetInitialOffset,
+				OffsetInitialOffset: 42,
+			},
+			wantResult: map[string]int64{topicB + "/0": 42},
+		},
+		{
+			name: "Timestamp after last message",
+			args: &config.Config{
+				OffsetInitial:          config.OffsetInitialTimestamp,
+				OffsetInitialTimestamp: "2006-01-02T15:04:05Z",
+			},
+			wantResult: map[string]int64{topicB + "/0": 100},
+		},
+		{
+			name: "Reset to oldest",
+			args: &config.Config{
+				OffsetInitial: config.OffsetInitialOldest,
+				OffsetReset:   true,
+			},
+			wantResult: map[string]int64{topicA + "/0": 10, topicB + "/0": 10},
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			k := New(context.Background(), tc.args)
+			k.client = &clientStub{}
+			k.admin = &adminStub{committed: map[string]map[int32]int64{
+				topicA: {0: 50},
+				topicB: {0: -1},
+			}}
+			session := &sessionStub{claims: map[string][]int32{
+				topicA: {0},
+				topicB: {0},
+			}}
+
+			require.NoError(t, k.position(session))
+			assert.Equal(t, tc.wantResult, session.offsetList)
+
+			// reset happens once per start
+			session.offsetList = nil
+			require.NoError(t, k.position(session))
+			if tc.args.OffsetReset {
+				assert.Equal(t, map[string]int64{topicB + "/0": 10}, session.offsetList)
+			}
+		})
+	}
+}
+
+func TestKafka_receive(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      func() *sarama.ConsumerMessage
+		wantValue any
+		wantError bool
+	}{
+		{
+			name: "Request",
+			args: func() *sarama.ConsumerMessage {
+				value := &bytes.Buffer{}
+				require.NoError(t, gob.NewEncoder(value).Encode(protocol.Request{Payload: []int64{1}}))
+
+				return &sarama.ConsumerMessage{Topic: topicA, Value: value.Bytes()}
+			},
+			wantValue: protocol.Request{Payload: []int64{1}},
+		},
+		{
+			name: "Response",
+			args: func() *sarama.ConsumerMessage {
+				return &sarama.ConsumerMessage{Topic: topicB, Value: []byte(`{"Payload": 6}`)}
+			},
+			wantValue: protocol.Response{Payload: 6},
+		},
+		{
+			name: "Retried request",
+			args: func() *sarama.ConsumerMessage {
+				value := &bytes.Buffer{}
+				require.NoError(t, gob.NewEncoder(value).Encode(protocol.Request{Payload: []int64{1}}))
+
+				return &sarama.ConsumerMessage{
+					Topic:   retry.Topic(topicA, 1),
+					Value:   value.Bytes(),
+					Headers: []*sarama.RecordHeader{{Key: []byte(header.OriginalTopic), Value: []byte(topicA)}},
+				}
+			},
+			wantValue: protocol.Request{Payload: []int64{1}},
+		},
+		{
+			name: "Declared JSON request",
+			args: func() *sarama.ConsumerMessage {
+				return &sarama.ConsumerMessage{
+					Topic: topicA,
+					Value: []byte(`{"Payload": [1]}`),
+					Headers: []*sarama.RecordHeader{
+						{Key: []byte(header.MessageType), Value: []byte(topic.MessageRequest)},
+						{Key: []byte(header.ContentType), Value: []byte(config.CodecJSON)},
+					},
+				}
+			},
+			wantValue: protocol.Request{Payload: []int64{1}},
+		},
+		{
+			name: "Declared type mismatch",
+			args: func() *sarama.ConsumerMessage {
+				return &sarama.ConsumerMessage{
+					Topic:   topicA,
+					Value:   []byte(`{"Payload": 6}`),
+					Headers: []*sarama.RecordHeader{{Key: []byte(header.MessageType), Value: []byte(topic.MessageResponse)}},
+				}
+			},
+			wantError: true,
+		},
+		{
+			name: "Unsupported schema version",
+			args: func() *sarama.ConsumerMessage {
+				return &sarama.ConsumerMessage{
+					Topic:   topicB,
+					Value:   []byte(`{"Payload": 6}`),
+					Headers: []*sarama.RecordHeader{{Key: []byte(header.SchemaVersion), Value: []byte("example")}},
+				}
+			},
+			wantError: true,
+		},
+		{
+			name: "Schema mismatch",
+			args: func() *sarama.ConsumerMessage {
+				value := &bytes.Buffer{}
+				require.NoError(t, gob.NewEncoder(value).Encode(protocol.Response{Payload: 6}))
+
+				return &sarama.ConsumerMessage{Topic: topicA, Value: value.Bytes()}
+			},
+			wantError: true,
+		},
+		{
+			name: "Unknown topic",
+			args: func() *sarama.ConsumerMessage {
+				return &sarama.ConsumerMessage{Topic: "example"}
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			k := New(context.Background(), &config.Config{})
+			k.registry = newRegistry(t)
+			var received any
+			k.handlerList[topic.MessageRequest] = func(_ context.Context, _ *sarama.ConsumerMessage, value any) error {
+				received = value
+
+				return nil
+			}
+			k.handlerList[topic.MessageResponse] = k.handlerList[topic.MessageRequest]
+
+			err := k.receive(context.Background(), tc.args())
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantValue, received)
+		})
+	}
+
+	t.Run("Continues producer trace", func(t *testing.T) {
+		k := New(context.Background(), &config.Config{})
+		k.registry = newRegistry(t)
+		var received tracer.SpanContext
+		k.handlerList[topic.MessageResponse] = func(ctx context.Context, _ *sarama.ConsumerMessage, _ any) error {
+			received = tracer.SpanContextFromContext(ctx)
+
+			return nil
+		}
+		_, span := tracer.Start(context.Background(), "example")
+		headerList := header.New(
+			tracer.ContextWithSpanContext(context.Background(), span.SpanContext()),
+			topic.MessageResponse,
+			config.CodecJSON,
+			"example",
+		)
+		msg := &sarama.ConsumerMessage{Topic: topicB, Value: []byte(`{"Payload": 6}`)}
+		for i := range headerList {
+			msg.Headers = append(msg.Headers, &headerList[i])
+		}
+
+		require.NoError(t, k.receive(context.Background(), msg))
+		assert.Equal(t, span.SpanContext().TraceID, received.TraceID)
+		assert.NotEqual(t, span.SpanContext().SpanID, received.SpanID)
+	})
+}
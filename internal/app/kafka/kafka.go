@@ -2,20 +2,24 @@
 package kafka
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
 	"fmt"
-	"time"
+	"sync"
 
 	"github.com/IBM/sarama"
-	"github.com/google/uuid"
 	"github.com/pkg/errors"
 
 	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/factory"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/producer"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/retry"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/rpc"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/topic"
 	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
-	"github.com/kirill-a-belov/test_task_framework/pkg/context_helper"
+	"github.com/kirill-a-belov/test_task_framework/pkg/backoff"
 	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
+	"github.com/kirill-a-belov/test_task_framework/pkg/metrics"
+	"github.com/kirill-a-belov/test_task_framework/pkg/rand"
 	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
 )
 
@@ -23,55 +27,179 @@ func New(ctx context.Context, config *config.Config) *Kafka {
 	_, span := tracer.Start(ctx, "internal.app.kafka.New")
 	defer span.End()
 
-	return &Kafka{
+	k := &Kafka{
 		config:   config,
 		stopChan: make(chan struct{}),
 		logger:   logger.New("kafka"),
+		factory:  factory.Default(),
+
+		resetDoneList: make(map[string]struct{}),
+		processedList: make(map[string]int64),
+
+		consumedCounter: metrics.NewCounter("kafka.consumer.consumed"),
+		failedCounter:   metrics.NewCounter("kafka.consumer.failed"),
+		partitionGauge:  metrics.NewGauge("kafka.consumer.partitions"),
+
+		committedTxnCounter: metrics.NewCounter("kafka.transaction.committed"),
+		abortedTxnCounter:   metrics.NewCounter("kafka.transaction.aborted"),
+
+		driftGauge: metrics.NewGauge("kafka.topic.drift"),
+		lagGauge:   metrics.NewGauge("kafka.consumer.lag"),
+
+		metricsExporter: metrics.NewExporter(config.MetricsInterval),
 	}
+	k.handlerList = map[string]func(context.Context, *sarama.ConsumerMessage, any) error{
+		topic.MessageRequest: k.serveSum,
+	}
+
+	return k
 }
 
 type Kafka struct {
 	config   *config.Config
 	stopChan chan struct{}
 	logger   logger.Logger
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	factory  factory.Factory
 
-	producer         sarama.SyncProducer
-	consumer         sarama.Consumer
-	partConsumerList []sarama.PartitionConsumer
-}
+	client        sarama.Client
+	admin         sarama.ClusterAdmin
+	producer      producer.Producer
+	txnProducer   sarama.SyncProducer
+	consumer      sarama.Consumer
+	consumerGroup sarama.ConsumerGroup
+	rpc           *rpc.Client
+	retry         *retry.Pipeline
+	registry      *topic.Registry
+	handlerList   map[string]func(ctx context.Context, msg *sarama.ConsumerMessage, value any) error
 
-const (
-	topicA = "example_topic_a"
-	topicB = "example_topic_B"
-)
+	resetMu       sync.Mutex
+	resetDoneList map[string]struct{}
+	txnMu         sync.Mutex
+	processedMu   sync.Mutex
+	processedList map[string]int64
+
+	consumedCounter *metrics.Counter
+	failedCounter   *metrics.Counter
+	partitionGauge  *metrics.Gauge
+
+	committedTxnCounter *metrics.Counter
+	abortedTxnCounter   *metrics.Counter
+
+	driftGauge *metrics.Gauge
+	lagGauge   *metrics.Gauge
+
+	metricsExporter *metrics.Exporter
+}
 
 func (k *Kafka) Start(ctx context.Context) error {
 	_, span := tracer.Start(ctx, "internal.app.kafka.Kafka.Start")
 	defer span.End()
 
 	k.logger.Info(fmt.Sprintf("config: %+v", *k.config))
+	k.metricsExporter.Start(ctx)
 
-	kafkaServiceAddressList := []string{k.config.Address}
-	p, err := sarama.NewSyncProducer(kafkaServiceAddressList, nil)
+	topicSpecList, err := k.config.TopicSpecs()
 	if err != nil {
-		return errors.Wrap(err, "creating producer")
+		return errors.Wrap(err, "parsing topic list")
+	}
+	if k.registry, err = topic.New(topicSpecList); err != nil {
+		return errors.Wrap(err, "creating topic registry")
+	}
+	if err := k.registry.Check(k.config.RequestTopic, protocol.Request{}); err != nil {
+		return errors.Wrap(err, "checking request topic")
+	}
+	if err := k.registry.Check(k.config.ReplyTopic, protocol.Response{}); err != nil {
+		return errors.Wrap(err, "checking reply topic")
+	}
+
+	saramaConfig, err := k.config.SaramaConfig()
+	if err != nil {
+		return errors.Wrap(err, "creating sarama config")
+	}
+
+	kafkaServiceAddressList := []string{k.config.Address}
+	if k.client, err = k.factory.Client(kafkaServiceAddressList, saramaConfig); err != nil {
+		return errors.Wrap(err, "creating client")
+	}
+	if k.admin, err = k.factory.ClusterAdmin(k.client); err != nil {
+		return errors.Wrap(err, "creating cluster admin")
+	}
+	if k.config.TopicProvision {
+		if err := k.provision(ctx); err != nil {
+			return errors.Wrap(err, "provisioning topics")
+		}
+	}
+
+	if k.config.ProducerMode == config.ProducerModeAsync {
+		p, err := k.factory.AsyncProducer(k.client)
+		if err != nil {
+			return errors.Wrap(err, "creating async producer")
+		}
+		k.producer = producer.New(ctx, p)
+	} else {
+		p, err := k.factory.SyncProducer(k.client)
+		if err != nil {
+			return errors.Wrap(err, "creating producer")
+		}
+		k.producer = p
+	}
+
+	if k.config.TransactionalID != "" {
+		txnConfig, err := k.config.TransactionalSaramaConfig()
+		if err != nil {
+			return errors.Wrap(err, "creating transactional sarama config")
+		}
+		// transactional producer sends results of consumed messages only, rpc requests are sent outside of transactions
+		if k.txnProducer, err = k.factory.TxnProducer(kafkaServiceAddressList, txnConfig); err != nil {
+			return errors.Wrap(err, "creating transactional producer")
+		}
 	}
-	k.producer = p
-	go k.processor(ctx, sender(k.producer))
+	k.retry = retry.New(ctx, k.config, k.output())
 
-	c, err := sarama.NewConsumer(kafkaServiceAddressList, nil)
+	c, err := k.factory.Consumer(k.client)
 	if err != nil {
 		return errors.Wrap(err, "creating consumer")
 	}
 	k.consumer = c
+	k.rpc = rpc.NewClient(ctx, k.config, k.registry, k.producer, k.consumer)
+	if err := k.rpc.Start(ctx); err != nil {
+		return errors.Wrap(err, "starting rpc client")
+	}
 
-	topicList := []string{topicA, topicB}
-	k.partConsumerList = make([]sarama.PartitionConsumer, len(topicList))
-	for i, topic := range topicList {
-		if k.partConsumerList[i], err = k.consumer.ConsumePartition(topic, 0, sarama.OffsetNewest); err != nil {
-			return errors.Wrapf(err, "creating consumer for topic (%s)", topic)
+	cg, err := k.factory.ConsumerGroup(k.config.GroupID, k.client)
+	if err != nil {
+		return errors.Wrap(err, "creating consumer group")
+	}
+	k.consumerGroup = cg
+
+	// loops run under cancellable context and are waited by Stop before producers are closed
+	ctx, k.cancel = context.WithCancel(ctx)
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+		k.processor(ctx, k.request(ctx))
+	}()
+
+	topicList := append([]string{k.config.RequestTopic}, k.retry.Topics(k.config.RequestTopic)...)
+	k.wg.Add(2)
+	go func() {
+		defer k.wg.Done()
+		k.consume(ctx, topicList)
+	}()
+	go func() {
+		defer k.wg.Done()
+		for err := range k.consumerGroup.Errors() {
+			k.logger.Error(err, "consumer group")
 		}
-		go k.processor(ctx, receiver(k.partConsumerList[i]))
+	}()
+	if k.config.LagInterval > 0 {
+		k.wg.Add(1)
+		go func() {
+			defer k.wg.Done()
+			k.monitorLag(ctx, topicList)
+		}()
 	}
 
 	return nil
@@ -81,20 +209,29 @@ func (k *Kafka) processor(ctx context.Context, interactor func() error) {
 	_, span := tracer.Start(ctx, "internal.app.kafka.Kafka.processor")
 	defer span.End()
 
+	retryPolicy := k.config.RetryPolicy()
+	failedCnt := 0
 	for {
-		select {
-		case <-k.stopChan:
+		// interactor is bounded by connection TTL itself, it is not abandoned to keep it off closed producers
+		err := interactor()
+		if ctx.Err() != nil {
+			k.logger.Info("processor terminated")
+
+			return
+		}
+
+		delay := k.config.Delay
+		if err != nil {
+			failedCnt++
+			k.logger.Error(err, "kafka interaction", "attempt", failedCnt)
+			delay += retryPolicy.Delay(failedCnt)
+		} else {
+			failedCnt = 0
+		}
+		if err := backoff.Wait(ctx, delay); err != nil {
 			k.logger.Info("processor terminated")
 
 			return
-		default:
-			if err := context_helper.RunWithTimeout(k.config.ConnTTL, func() error {
-				return interactor()
-			}); err != nil {
-				k.logger.Error(err, "kafka interaction")
-			}
-
-			time.Sleep(k.config.Delay)
 		}
 	}
 }
@@ -103,80 +240,57 @@ func (k *Kafka) Stop(ctx context.Context) {
 	_, span := tracer.Start(ctx, "internal.app.kafka.Kafka.Stop")
 	defer span.End()
 
-	for _, pc := range k.partConsumerList {
-		_ = pc.Close()
+	if k.cancel != nil {
+		k.cancel()
+	}
+	if k.consumerGroup != nil {
+		_ = k.consumerGroup.Close()
+	}
+	k.wg.Wait()
+	if k.rpc != nil {
+		k.rpc.Stop(ctx)
+	}
+	if k.consumer != nil {
+		_ = k.consumer.Close()
+	}
+	if k.txnProducer != nil {
+		_ = k.txnProducer.Close()
 	}
-	_ = k.consumer.Close()
-	_ = k.producer.Close()
+	if k.producer != nil {
+		// async producer flushes buffered messages before closing
+		if err := k.producer.Close(); err != nil {
+			k.logger.Error(err, "closing producer")
+		}
+	}
+	if k.client != nil {
+		_ = k.client.Close()
+	}
+	k.metricsExporter.Stop(ctx)
 
 	close(k.stopChan)
 }
 
-func sender(producer sarama.SyncProducer) func() error {
+// request sends demo sum request and waits for reply
+func (k *Kafka) request(ctx context.Context) func() error {
 	return func() error {
-		uuidA := uuid.NewString()
-		msgA := &bytes.Buffer{}
-		if err := gob.NewEncoder(msgA).
-			Encode(protocol.Message{
-				Type: protocol.MessageTypeRequest,
-			},
-			); err != nil {
-			return errors.Wrap(err, "encoding msg A")
-		}
-		uuidB := uuid.NewString()
-		msgB := &bytes.Buffer{}
-		if err := gob.NewEncoder(msgB).
-			Encode(protocol.Message{
-				Type: protocol.MessageTypeResponse,
-			},
-			); err != nil {
-			return errors.Wrap(err, "encoding msg B")
-		}
+		ctx, cancel := context.WithTimeout(ctx, k.config.ConnTTL)
+		defer cancel()
 
-		msgList := []*sarama.ProducerMessage{
-			{
-				Topic: topicA,
-				Key:   sarama.StringEncoder(uuidA),
-				Value: sarama.ByteEncoder(msgA.Bytes()),
-			},
-			{
-				Topic: topicB,
-				Key:   sarama.StringEncoder(uuidB),
-				Value: sarama.ByteEncoder(msgB.Bytes()),
-			},
+		payload, err := rand.Rand(payloadSize, payloadMaxDigit)
+		if err != nil {
+			return errors.Wrap(err, "generating payload")
 		}
 
-		if err := producer.SendMessages(msgList); err != nil {
-			return errors.Wrap(err, "sending messages")
+		result, err := k.rpc.Sum(ctx, payload)
+		if err != nil {
+			return errors.Wrap(err, "requesting sum")
 		}
 
-		logger.New("kafka.sender").Info("sending messages",
-			"sent keys", uuidA, uuidB,
+		logger.New("kafka.request").Info("handling request",
+			"sent", payload,
+			"received", result,
 		)
 
 		return nil
 	}
 }
-
-func receiver(consumer sarama.PartitionConsumer) func() error {
-	return func() error {
-		msg, ok := <-consumer.Messages()
-		if !ok {
-			return errors.New("reading msg from consumer chan")
-		}
-
-		msgValue := &protocol.Request{}
-		msgB := &bytes.Buffer{}
-		if _, err := msgB.Write(msg.Value); err != nil {
-			return errors.Wrap(err, "writing msg buffer")
-		}
-		if err := gob.NewDecoder(msgB).Decode(msgValue); err != nil {
-			return errors.Wrap(err, "decoding msg value")
-		}
-
-		logger.New("kafka.receiver").Info("receiving message",
-			"received key", string(msg.Key))
-
-		return nil
-	}
-}
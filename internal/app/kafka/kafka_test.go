@@ -0,0 +1,215 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/harnesstest"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/header"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+)
+
+func newConfig() *config.Config {
+	return &config.Config{
+		Delay:        10 * time.Millisecond,
+		ConnTTL:      time.Second,
+		TopicList:    []string{topicA + ":request:gob", topicB + ":response:json"},
+		RequestTopic: topicA,
+		ReplyTopic:   topicB,
+		ReplyTimeout: time.Second,
+		GroupID:      "example",
+		RetryBase:    10 * time.Millisecond,
+		RetryMax:     50 * time.Millisecond,
+	}
+}
+
+func TestKafka_Start(t *testing.T) {
+	testCaseList := []struct {
+		name    string
+		config  func(cfg *config.Config)
+		fail    func(t *testing.T, h *harnesstest.Harness)
+		recover func(t *testing.T, h *harnesstest.Harness)
+	}{
+		{
+			name: "Sync producer",
+		},
+		{
+			name: "Async producer",
+			config: func(cfg *config.Config) {
+				cfg.ProducerMode = config.ProducerModeAsync
+			},
+		},
+		{
+			name: "Transactional producer",
+			config: func(cfg *config.Config) {
+				cfg.TransactionalID = "example"
+			},
+		},
+		{
+			name: "Topic provisioning",
+			config: func(cfg *config.Config) {
+				cfg.TopicProvision = true
+				cfg.TopicRetention = time.Hour
+			},
+		},
+		{
+			name: "Lag monitoring",
+			config: func(cfg *config.Config) {
+				cfg.LagInterval = 10 * time.Millisecond
+			},
+		},
+		{
+			name: "Produce failure",
+			fail: func(_ *testing.T, h *harnesstest.Harness) {
+				h.FailProduce(sarama.ErrNotEnoughReplicas)
+			},
+			recover: func(_ *testing.T, h *harnesstest.Harness) {
+				h.FailProduce(nil)
+			},
+		},
+		{
+			name: "Broker error on positioning",
+			config: func(cfg *config.Config) {
+				cfg.OffsetInitial = config.OffsetInitialOldest
+				cfg.OffsetReset = true
+			},
+			fail: func(_ *testing.T, h *harnesstest.Harness) {
+				h.SetHandler("OffsetRequest", sarama.NewMockWrapper(&sarama.OffsetResponse{
+					Version: 4,
+					Blocks: map[string]map[int32]*sarama.OffsetResponseBlock{
+						topicA: {0: {Err: sarama.ErrNotLeaderForPartition}},
+					},
+				}))
+			},
+			recover: func(t *testing.T, h *harnesstest.Harness) {
+				h.SetHandler("OffsetRequest", sarama.NewMockOffsetResponse(t).SetOffset(topicA, 0, sarama.OffsetOldest, 0))
+			},
+		},
+		{
+			name: "Session failure",
+			fail: func(_ *testing.T, h *harnesstest.Harness) {
+				h.FailConsume(assert.AnError)
+			},
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := newConfig()
+			if tc.config != nil {
+				tc.config(cfg)
+			}
+			h := harnesstest.New(t, cfg)
+			if tc.fail != nil {
+				tc.fail(t, h)
+			}
+
+			k := New(ctx, cfg)
+			k.factory = h.Factory()
+			require.NoError(t, k.Start(ctx))
+			defer k.Stop(ctx)
+
+			if tc.recover != nil {
+				failedCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+				_, err := k.rpc.Sum(failedCtx, []int64{1, 2, 3})
+				cancel()
+				assert.Error(t, err)
+
+				tc.recover(t, h)
+			}
+
+			result, err := k.rpc.Sum(ctx, []int64{1, 2, 3})
+			require.NoError(t, err)
+			assert.Equal(t, int64(6), result)
+			assert.Eventually(t, func() bool {
+				return h.Marked(topicA) > 0
+			}, time.Second, 10*time.Millisecond)
+		})
+	}
+
+	t.Run("Client failure", func(t *testing.T) {
+		ctx := context.Background()
+		cfg := newConfig()
+		h := harnesstest.New(t, cfg)
+
+		k := New(ctx, cfg)
+		k.factory = h.Factory()
+		k.factory.Client = func([]string, *sarama.Config) (sarama.Client, error) {
+			return nil, sarama.ErrOutOfBrokers
+		}
+
+		assert.ErrorIs(t, k.Start(ctx), sarama.ErrOutOfBrokers)
+		k.Stop(ctx)
+	})
+}
+
+func TestKafka_Stop(t *testing.T) {
+	t.Run("Request loop stopped before producer", func(t *testing.T) {
+		ctx := context.Background()
+		cfg := newConfig()
+		cfg.Delay = time.Millisecond
+		cfg.ProducerMode = config.ProducerModeAsync
+		h := harnesstest.New(t, cfg)
+
+		k := New(ctx, cfg)
+		k.factory = h.Factory()
+		require.NoError(t, k.Start(ctx))
+		require.True(t, h.WaitProduced(topicA, 3, time.Second))
+
+		k.Stop(ctx)
+		producedCnt := len(h.Produced(topicA))
+		time.Sleep(100 * time.Millisecond)
+		assert.Len(t, h.Produced(topicA), producedCnt)
+	})
+}
+
+func TestKafka_Start_feed(t *testing.T) {
+	ctx := context.Background()
+	cfg := newConfig()
+	cfg.Delay = time.Second
+	h := harnesstest.New(t, cfg)
+
+	value := &bytes.Buffer{}
+	require.NoError(t, gob.NewEncoder(value).Encode(protocol.Request{
+		Message: protocol.Message{
+			Type: protocol.MessageTypeRequest,
+		},
+		Payload: []int64{1, 2, 3},
+	}))
+	h.Feed(&sarama.ConsumerMessage{
+		Topic: topicA,
+		Value: value.Bytes(),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(header.CorrelationID), Value: []byte("example")},
+		},
+	})
+
+	k := New(ctx, cfg)
+	k.factory = h.Factory()
+	require.NoError(t, k.Start(ctx))
+	defer k.Stop(ctx)
+
+	require.Eventually(t, func() bool {
+		for _, msg := range h.Produced(topicB) {
+			for _, recordHeader := range msg.Headers {
+				if string(recordHeader.Key) == header.CorrelationID && string(recordHeader.Value) == "example" {
+					return true
+				}
+			}
+		}
+
+		return false
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return h.Marked(topicA) > 0
+	}, time.Second, 10*time.Millisecond)
+}
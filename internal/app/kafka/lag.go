@@ -0,0 +1,101 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/lag"
+	"github.com/kirill-a-belov/test_task_framework/pkg/metrics"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+// monitorLag reports consumer lag of topic list every lag interval until context is cancelled
+func (k *Kafka) monitorLag(ctx context.Context, topicList []string) {
+	_, span := tracer.Start(ctx, "internal.app.kafka.Kafka.monitorLag")
+	defer span.End()
+
+	ticker := time.NewTicker(k.config.LagInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := k.reportLag(ctx, topicList); err != nil {
+				k.logger.Error(err, "reporting consumer lag")
+			}
+		}
+	}
+}
+
+// reportLag sets lag gauges of every partition and their total, processed offsets not committed yet
+// are taken into account, partitions lagging above threshold are logged
+func (k *Kafka) reportLag(ctx context.Context, topicList []string) ([]lag.Partition, error) {
+	ctx, span := tracer.Start(ctx, "internal.app.kafka.Kafka.reportLag")
+	defer span.End()
+
+	partitionList, err := lag.Measure(ctx, k.client, k.admin, k.config.GroupID, topicList)
+	if err != nil {
+		return nil, errors.Wrap(err, "measuring lag")
+	}
+
+	total := int64(0)
+	for i := range partitionList {
+		p := &partitionList[i]
+		if offset, ok := k.processed(p.Topic, p.Partition); ok {
+			p.Processed(offset)
+		}
+		total += p.Lag
+		metrics.NewGauge(fmt.Sprintf("kafka.consumer.lag.%s.%d", p.Topic, p.Partition)).Set(p.Lag)
+
+		if k.config.LagWarnThreshold > 0 && p.Lag > k.config.LagWarnThreshold {
+			k.logger.Error(lag.ErrThreshold,
+				"group", p.Group,
+				"topic", p.Topic,
+				"partition", p.Partition,
+				"lag", p.Lag,
+				"threshold", k.config.LagWarnThreshold,
+			)
+		}
+	}
+	k.lagGauge.Set(total)
+
+	return partitionList, nil
+}
+
+// markProcessed remembers offset of the next message to process in partition
+func (k *Kafka) markProcessed(msg *sarama.ConsumerMessage) {
+	k.processedMu.Lock()
+	defer k.processedMu.Unlock()
+
+	k.processedList[processedKey(msg.Topic, msg.Partition)] = msg.Offset + 1
+}
+
+// forgetProcessed drops offsets of revoked partitions, their new owner reports processing progress
+func (k *Kafka) forgetProcessed(claims map[string][]int32) {
+	k.processedMu.Lock()
+	defer k.processedMu.Unlock()
+
+	for topic, partitionList := range claims {
+		for _, partition := range partitionList {
+			delete(k.processedList, processedKey(topic, partition))
+		}
+	}
+}
+
+func (k *Kafka) processed(topic string, partition int32) (int64, bool) {
+	k.processedMu.Lock()
+	defer k.processedMu.Unlock()
+
+	offset, ok := k.processedList[processedKey(topic, partition)]
+
+	return offset, ok
+}
+
+func processedKey(topic string, partition int32) string {
+	return fmt.Sprintf("%s/%d", topic, partition)
+}
@@ -0,0 +1,82 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/harnesstest"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/lag"
+	"github.com/kirill-a-belov/test_task_framework/pkg/metrics"
+	"github.com/kirill-a-belov/test_task_framework/pkg/test_helper"
+)
+
+func TestKafka_reportLag(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      func(k *Kafka)
+		wantLag   int64
+		wantWarns int
+	}{
+		{
+			name:    "Committed offset",
+			wantLag: 5,
+		},
+		{
+			name: "Processed offset",
+			args: func(k *Kafka) {
+				k.markProcessed(&sarama.ConsumerMessage{Topic: topicA, Offset: 2})
+			},
+			wantLag: 2,
+		},
+		{
+			name: "Above threshold",
+			args: func(k *Kafka) {
+				k.config.LagWarnThreshold = 4
+			},
+			wantLag:   5,
+			wantWarns: 1,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newConfig()
+			h := harnesstest.New(t, cfg)
+			h.SetHandler("OffsetRequest", sarama.NewMockOffsetResponse(t).
+				SetOffset(topicA, 0, sarama.OffsetOldest, 0).
+				SetOffset(topicA, 0, sarama.OffsetNewest, 5))
+
+			loggerMock := &test_helper.LoggerMock{}
+			if tc.wantWarns > 0 {
+				loggerMock.On("Error", lag.ErrThreshold, mock.Anything).Times(tc.wantWarns)
+			}
+			k := New(context.Background(), cfg)
+			k.logger = loggerMock
+			var err error
+			k.client, err = h.Factory().Client(nil, sarama.NewConfig())
+			require.NoError(t, err)
+			defer func() {
+				_ = k.client.Close()
+			}()
+			k.admin, err = sarama.NewClusterAdminFromClient(k.client)
+			require.NoError(t, err)
+			if tc.args != nil {
+				tc.args(k)
+			}
+
+			result, err := k.reportLag(context.Background(), []string{topicA})
+
+			require.NoError(t, err)
+			require.Len(t, result, 1)
+			assert.Equal(t, tc.wantLag, result[0].Lag)
+			assert.Equal(t, tc.wantLag, k.lagGauge.Value())
+			assert.Equal(t, tc.wantLag, metrics.NewGauge("kafka.consumer.lag."+topicA+".0").Value())
+			loggerMock.AssertExpectations(t)
+		})
+	}
+}
@@ -2,23 +2,303 @@ package config
 
 import (
 	"context"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/IBM/sarama"
 	"github.com/caarlos0/env"
 	"github.com/go-playground/validator/v10"
 	"github.com/pkg/errors"
 
+	"github.com/kirill-a-belov/test_task_framework/pkg/backoff"
 	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
 )
 
+const (
+	RebalanceStrategyRange      = "range"
+	RebalanceStrategyRoundRobin = "round_robin"
+	RebalanceStrategySticky     = "sticky"
+
+	CodecGob  = "gob"
+	CodecJSON = "json"
+
+	OffsetCommitAuto   = "auto"
+	OffsetCommitManual = "manual"
+
+	OffsetInitialOldest    = "oldest"
+	OffsetInitialNewest    = "newest"
+	OffsetInitialOffset    = "offset"
+	OffsetInitialTimestamp = "timestamp"
+
+	ProducerModeSync  = "sync"
+	ProducerModeAsync = "async"
+
+	ProducerAcksNone   = "none"
+	ProducerAcksLeader = "leader"
+	ProducerAcksAll    = "all"
+
+	CleanupPolicyDelete  = "delete"
+	CleanupPolicyCompact = "compact"
+)
+
+var compressionCodecList = map[string]sarama.CompressionCodec{
+	"none":   sarama.CompressionNone,
+	"gzip":   sarama.CompressionGZIP,
+	"snappy": sarama.CompressionSnappy,
+	"lz4":    sarama.CompressionLZ4,
+	"zstd":   sarama.CompressionZSTD,
+}
+
+var requiredAcksList = map[string]sarama.RequiredAcks{
+	ProducerAcksNone:   sarama.NoResponse,
+	ProducerAcksLeader: sarama.WaitForLocal,
+	ProducerAcksAll:    sarama.WaitForAll,
+}
+
 type Config struct {
 	Address string        `env:"KAFKA_ADDRESS" validate:"hostname_port"`
 	Delay   time.Duration `env:"KAFKA_DELAY" validate:"gte=1ms,lte=1s"`
 	ConnTTL time.Duration `env:"KAFKA_CONN_TTL" validate:"gte=1ms,lte=1s"`
+
+	TopicList []string `env:"KAFKA_TOPIC_LIST" envDefault:"example_topic_a:request:gob,example_topic_B:response:gob"`
+
+	// topic settings used when topic list entry doesn't set them, zero retention and empty cleanup policy keep broker defaults
+	TopicPartitions        int           `env:"KAFKA_TOPIC_PARTITIONS" envDefault:"1" validate:"gte=0"`
+	TopicReplicationFactor int           `env:"KAFKA_TOPIC_REPLICATION_FACTOR" envDefault:"1" validate:"gte=0"`
+	TopicRetention         time.Duration `env:"KAFKA_TOPIC_RETENTION" validate:"gte=0"`
+	TopicCleanupPolicy     string        `env:"KAFKA_TOPIC_CLEANUP_POLICY" validate:"omitempty,oneof=delete compact"`
+	TopicProvision         bool          `env:"KAFKA_TOPIC_PROVISION"`
+	TopicProvisionDryRun   bool          `env:"KAFKA_TOPIC_PROVISION_DRY_RUN"`
+
+	RequestTopic string        `env:"KAFKA_REQUEST_TOPIC" envDefault:"example_topic_a"`
+	ReplyTopic   string        `env:"KAFKA_REPLY_TOPIC" envDefault:"example_topic_B"`
+	ReplyTimeout time.Duration `env:"KAFKA_REPLY_TIMEOUT" envDefault:"5s" validate:"gte=0"`
+
+	GroupID           string `env:"KAFKA_GROUP_ID" envDefault:"kafka-service"`
+	RebalanceStrategy string `env:"KAFKA_REBALANCE_STRATEGY" validate:"omitempty,oneof=range round_robin sticky"`
+
+	OffsetCommit             string        `env:"KAFKA_OFFSET_COMMIT" validate:"omitempty,oneof=auto manual"`
+	OffsetAutoCommitInterval time.Duration `env:"KAFKA_OFFSET_AUTO_COMMIT_INTERVAL" envDefault:"1s" validate:"gte=0"`
+	OffsetInitial            string        `env:"KAFKA_OFFSET_INITIAL" validate:"omitempty,oneof=oldest newest offset timestamp"`
+	OffsetInitialOffset      int64         `env:"KAFKA_OFFSET_INITIAL_OFFSET" validate:"gte=0"`
+	OffsetInitialTimestamp   string        `env:"KAFKA_OFFSET_INITIAL_TIMESTAMP" validate:"required_if=OffsetInitial timestamp,omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	OffsetReset              bool          `env:"KAFKA_OFFSET_RESET"`
+
+	// LagInterval is period of consumer lag reporting, zero disables it, zero threshold disables lag warnings
+	LagInterval      time.Duration `env:"KAFKA_LAG_INTERVAL" envDefault:"30s" validate:"gte=0"`
+	LagWarnThreshold int64         `env:"KAFKA_LAG_WARN_THRESHOLD" envDefault:"1000" validate:"gte=0"`
+
+	// MetricsInterval is period of metrics logging, zero disables it
+	MetricsInterval time.Duration `env:"KAFKA_METRICS_INTERVAL" envDefault:"1m" validate:"gte=0"`
+
+	// ProducerID is sent in headers of produced messages, host name by default
+	ProducerID string `env:"KAFKA_PRODUCER_ID"`
+
+	ProducerMode        string        `env:"KAFKA_PRODUCER_MODE" validate:"omitempty,oneof=sync async"`
+	ProducerBatchSize   int           `env:"KAFKA_PRODUCER_BATCH_SIZE" validate:"gte=0"`
+	ProducerLinger      time.Duration `env:"KAFKA_PRODUCER_LINGER" validate:"gte=0"`
+	ProducerCompression string        `env:"KAFKA_PRODUCER_COMPRESSION" validate:"omitempty,oneof=none gzip snappy lz4 zstd"`
+	ProducerAcks        string        `env:"KAFKA_PRODUCER_ACKS" validate:"omitempty,oneof=none leader all"`
+	ProducerMaxInFlight int           `env:"KAFKA_PRODUCER_MAX_IN_FLIGHT" validate:"gte=0"`
+	ProducerIdempotent  bool          `env:"KAFKA_PRODUCER_IDEMPOTENT"`
+	TransactionalID     string        `env:"KAFKA_TRANSACTIONAL_ID"`
+
+	SASLMechanism    string `env:"KAFKA_SASL_MECHANISM" validate:"omitempty,oneof=PLAIN SCRAM-SHA-256 SCRAM-SHA-512"`
+	SASLUsername     string `env:"KAFKA_SASL_USERNAME" validate:"required_with=SASLMechanism"`
+	SASLUsernameFile string `env:"KAFKA_SASL_USERNAME_FILE"`
+	SASLPassword     string `env:"KAFKA_SASL_PASSWORD" validate:"required_with=SASLMechanism"`
+	SASLPasswordFile string `env:"KAFKA_SASL_PASSWORD_FILE"`
+
+	TLSEnable             bool   `env:"KAFKA_TLS_ENABLE"`
+	TLSCAFile             string `env:"KAFKA_TLS_CA_FILE"`
+	TLSCertFile           string `env:"KAFKA_TLS_CERT_FILE" validate:"required_with=TLSKeyFile"`
+	TLSKeyFile            string `env:"KAFKA_TLS_KEY_FILE" validate:"required_with=TLSCertFile"`
+	TLSServerName         string `env:"KAFKA_TLS_SERVER_NAME"`
+	TLSInsecureSkipVerify bool   `env:"KAFKA_TLS_INSECURE_SKIP_VERIFY"`
+
+	RetryTopicDelayList []time.Duration `env:"KAFKA_RETRY_TOPIC_DELAY_LIST" validate:"dive,gt=0"`
+	DeadLetter          bool            `env:"KAFKA_DEAD_LETTER"`
+
+	RetryBase        time.Duration `env:"KAFKA_RETRY_BASE" envDefault:"100ms" validate:"gt=0"`
+	RetryMax         time.Duration `env:"KAFKA_RETRY_MAX" envDefault:"5s" validate:"gtefield=RetryBase"`
+	RetryJitter      float64       `env:"KAFKA_RETRY_JITTER" envDefault:"0.2" validate:"gte=0,lte=1"`
+	RetryMaxAttempts int           `env:"KAFKA_RETRY_MAX_ATTEMPTS" envDefault:"3" validate:"gte=0,lte=100"`
 }
 
 func (c *Config) validate() error {
-	return validator.New(validator.WithRequiredStructEnabled()).Struct(c)
+	if err := validator.New(validator.WithRequiredStructEnabled()).Struct(c); err != nil {
+		return err
+	}
+	if _, err := c.TopicSpecs(); err != nil {
+		return errors.Wrap(err, "topic list")
+	}
+
+	return nil
+}
+
+// TopicSpec binds topic to message type and codec, other settings are applied by topic provisioning
+type TopicSpec struct {
+	Name              string        `validate:"required"`
+	Message           string        `validate:"oneof=request response"`
+	Codec             string        `validate:"oneof=gob json"`
+	Partitions        int32         `validate:"gte=1"`
+	ReplicationFactor int16         `validate:"gte=1"`
+	Retention         time.Duration `validate:"gte=0"`
+	CleanupPolicy     string        `validate:"omitempty,oneof=delete compact"`
+}
+
+// TopicSpecs parses topic list of "name:message[:codec[:partitions[:replication[:retention[:cleanup]]]]]" entries,
+// empty or omitted settings are taken from topic defaults, gob codec is used by default
+func (c *Config) TopicSpecs() ([]TopicSpec, error) {
+	v := validator.New()
+	known := make(map[string]struct{}, len(c.TopicList))
+	result := make([]TopicSpec, 0, len(c.TopicList))
+	for _, entry := range c.TopicList {
+		partList := strings.Split(entry, ":")
+		if len(partList) < 2 || len(partList) > 7 {
+			return nil, errors.Errorf("invalid topic (%s)", entry)
+		}
+		partList = append(partList, make([]string, 7-len(partList))...)
+
+		spec := TopicSpec{
+			Name:              partList[0],
+			Message:           partList[1],
+			Codec:             CodecGob,
+			Partitions:        int32(max(c.TopicPartitions, 1)),
+			ReplicationFactor: int16(max(c.TopicReplicationFactor, 1)),
+			Retention:         c.TopicRetention,
+			CleanupPolicy:     c.TopicCleanupPolicy,
+		}
+		if partList[2] != "" {
+			spec.Codec = partList[2]
+		}
+		if partList[3] != "" {
+			partitions, err := strconv.ParseInt(partList[3], 10, 32)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid topic partitions (%s)", entry)
+			}
+			spec.Partitions = int32(partitions)
+		}
+		if partList[4] != "" {
+			replicationFactor, err := strconv.ParseInt(partList[4], 10, 16)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid topic replication factor (%s)", entry)
+			}
+			spec.ReplicationFactor = int16(replicationFactor)
+		}
+		if partList[5] != "" {
+			retention, err := time.ParseDuration(partList[5])
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid topic retention (%s)", entry)
+			}
+			spec.Retention = retention
+		}
+		if partList[6] != "" {
+			spec.CleanupPolicy = partList[6]
+		}
+		if err := v.Struct(spec); err != nil {
+			return nil, errors.Wrapf(err, "invalid topic (%s)", entry)
+		}
+		if _, ok := known[spec.Name]; ok {
+			return nil, errors.Errorf("duplicated topic (%s)", spec.Name)
+		}
+		known[spec.Name] = struct{}{}
+
+		result = append(result, spec)
+	}
+
+	return result, nil
+}
+
+func (c *Config) RetryPolicy() backoff.Policy {
+	return backoff.Policy{
+		Base:        c.RetryBase,
+		Max:         c.RetryMax,
+		Jitter:      c.RetryJitter,
+		MaxAttempts: c.RetryMaxAttempts,
+	}
+}
+
+// SaramaConfig returns sarama client config shared by producer, consumer and cluster admin
+func (c *Config) SaramaConfig() (*sarama.Config, error) {
+	cfg := sarama.NewConfig()
+	cfg.Producer.Return.Successes = true
+	cfg.Consumer.Return.Errors = true
+	cfg.Consumer.Offsets.Initial = sarama.OffsetNewest
+	if c.OffsetInitial == OffsetInitialOldest {
+		cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+	cfg.Consumer.Offsets.AutoCommit.Enable = c.OffsetCommit != OffsetCommitManual
+	if c.OffsetAutoCommitInterval > 0 {
+		cfg.Consumer.Offsets.AutoCommit.Interval = c.OffsetAutoCommitInterval
+	}
+
+	cfg.Producer.Flush.Messages = c.ProducerBatchSize
+	cfg.Producer.Flush.Frequency = c.ProducerLinger
+	if c.ProducerCompression != "" {
+		cfg.Producer.Compression = compressionCodecList[c.ProducerCompression]
+	}
+	if c.ProducerAcks != "" {
+		cfg.Producer.RequiredAcks = requiredAcksList[c.ProducerAcks]
+	}
+	if c.ProducerMaxInFlight > 0 {
+		cfg.Net.MaxOpenRequests = c.ProducerMaxInFlight
+	}
+	// explicitly configured acks and in-flight requests conflicting with idempotence fail validation
+	if c.ProducerIdempotent || c.TransactionalID != "" {
+		cfg.Producer.Idempotent = true
+		if c.ProducerAcks == "" {
+			cfg.Producer.RequiredAcks = sarama.WaitForAll
+		}
+		if c.ProducerMaxInFlight == 0 {
+			cfg.Net.MaxOpenRequests = 1
+		}
+	}
+	if c.TransactionalID != "" {
+		// replies of aborted transactions must not reach rpc clients
+		cfg.Consumer.IsolationLevel = sarama.ReadCommitted
+	}
+
+	if err := c.applySecurity(cfg); err != nil {
+		return nil, errors.Wrap(err, "configuring connection security")
+	}
+
+	switch c.RebalanceStrategy {
+	case RebalanceStrategyRoundRobin:
+		cfg.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}
+	case RebalanceStrategySticky:
+		cfg.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategySticky()}
+	default:
+		cfg.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRange()}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "sarama config validation")
+	}
+
+	return cfg, nil
+}
+
+// TransactionalSaramaConfig returns config of producer which sends consumed messages results in transactions
+func (c *Config) TransactionalSaramaConfig() (*sarama.Config, error) {
+	cfg, err := c.SaramaConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Producer.Transaction.ID = c.TransactionalID
+
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "sarama config validation")
+	}
+
+	return cfg, nil
+}
+
+// InitialTimestamp returns time consumption starts from in timestamp initial position
+func (c *Config) InitialTimestamp() (time.Time, error) {
+	return time.Parse(time.RFC3339, c.OffsetInitialTimestamp)
 }
 
 func (c *Config) Load(ctx context.Context) error {
@@ -28,6 +308,12 @@ func (c *Config) Load(ctx context.Context) error {
 	if err := env.Parse(c); err != nil {
 		return errors.Wrap(err, "config loading")
 	}
+	if c.ProducerID == "" {
+		c.ProducerID, _ = os.Hostname()
+	}
+	if err := c.resolveSecrets(); err != nil {
+		return errors.Wrap(err, "config loading")
+	}
 
 	if err := c.validate(); err != nil {
 		return errors.Wrap(err, "config validation")
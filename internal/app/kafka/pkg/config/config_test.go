@@ -4,7 +4,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/IBM/sarama"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig_validate(t *testing.T) {
@@ -16,36 +18,385 @@ func TestConfig_validate(t *testing.T) {
 		{
 			name: "Success",
 			args: Config{
-				Address: "localhost:1234",
-				Delay:   time.Second,
-				ConnTTL: time.Second,
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
 			},
 			wantError: false,
 		},
 		{
 			name: "Invalid address",
 			args: Config{
-				Address: "local:host:1234",
-				Delay:   time.Second,
-				ConnTTL: time.Second,
+				Address:   "local:host:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
 			},
 			wantError: true,
 		},
 		{
 			name: "Invalid delay value",
 			args: Config{
-				Address: "localhost:1234",
-				Delay:   time.Hour,
-				ConnTTL: time.Second,
+				Address:   "localhost:1234",
+				Delay:     time.Hour,
+				ConnTTL:   time.Second,
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
 			},
 			wantError: true,
 		},
 		{
 			name: "Invalid TTL",
 			args: Config{
-				Address: "localhost:1234",
-				Delay:   time.Second,
-				ConnTTL: time.Hour,
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Hour,
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with retry policy",
+			args: Config{
+				Address:          "localhost:1234",
+				Delay:            time.Second,
+				ConnTTL:          time.Second,
+				RetryBase:        time.Millisecond,
+				RetryMax:         time.Second,
+				RetryJitter:      0.5,
+				RetryMaxAttempts: 3,
+			},
+			wantError: false,
+		},
+		{
+			name: "Retry max below base",
+			args: Config{
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				RetryBase: time.Second,
+				RetryMax:  time.Millisecond,
+			},
+			wantError: true,
+		},
+		{
+			name: "Zero retry base",
+			args: Config{
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				RetryBase: 0,
+				RetryMax:  time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid retry jitter",
+			args: Config{
+				Address:     "localhost:1234",
+				Delay:       time.Second,
+				ConnTTL:     time.Second,
+				RetryJitter: 2,
+				RetryBase:   100 * time.Millisecond,
+				RetryMax:    time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with consumer group",
+			args: Config{
+				Address:           "localhost:1234",
+				Delay:             time.Second,
+				ConnTTL:           time.Second,
+				GroupID:           "example",
+				RebalanceStrategy: RebalanceStrategySticky,
+				RetryBase:         100 * time.Millisecond,
+				RetryMax:          time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Success with offset management",
+			args: Config{
+				Address:                  "localhost:1234",
+				Delay:                    time.Second,
+				ConnTTL:                  time.Second,
+				OffsetCommit:             OffsetCommitManual,
+				OffsetAutoCommitInterval: time.Second,
+				OffsetInitial:            OffsetInitialTimestamp,
+				OffsetInitialTimestamp:   "2006-01-02T15:04:05Z",
+				OffsetReset:              true,
+				RetryBase:                100 * time.Millisecond,
+				RetryMax:                 time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Missing initial timestamp",
+			args: Config{
+				Address:       "localhost:1234",
+				Delay:         time.Second,
+				ConnTTL:       time.Second,
+				OffsetInitial: OffsetInitialTimestamp,
+				RetryBase:     100 * time.Millisecond,
+				RetryMax:      time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid initial timestamp",
+			args: Config{
+				Address:                "localhost:1234",
+				Delay:                  time.Second,
+				ConnTTL:                time.Second,
+				OffsetInitial:          OffsetInitialTimestamp,
+				OffsetInitialTimestamp: "example",
+				RetryBase:              100 * time.Millisecond,
+				RetryMax:               time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid offset commit",
+			args: Config{
+				Address:      "localhost:1234",
+				Delay:        time.Second,
+				ConnTTL:      time.Second,
+				OffsetCommit: "example",
+				RetryBase:    100 * time.Millisecond,
+				RetryMax:     time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with topic list",
+			args: Config{
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				TopicList: []string{"a:request", "b:response:json"},
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Invalid topic message type",
+			args: Config{
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				TopicList: []string{"a:example"},
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid topic codec",
+			args: Config{
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				TopicList: []string{"a:request:example"},
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Duplicated topic",
+			args: Config{
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				TopicList: []string{"a:request", "a:response"},
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid reply timeout",
+			args: Config{
+				Address:      "localhost:1234",
+				Delay:        time.Second,
+				ConnTTL:      time.Second,
+				ReplyTimeout: -time.Second,
+				RetryBase:    100 * time.Millisecond,
+				RetryMax:     time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid rebalance strategy",
+			args: Config{
+				Address:           "localhost:1234",
+				Delay:             time.Second,
+				ConnTTL:           time.Second,
+				RebalanceStrategy: "example",
+				RetryBase:         100 * time.Millisecond,
+				RetryMax:          time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with async producer",
+			args: Config{
+				Address:             "localhost:1234",
+				Delay:               time.Second,
+				ConnTTL:             time.Second,
+				ProducerMode:        ProducerModeAsync,
+				ProducerBatchSize:   100,
+				ProducerLinger:      10 * time.Millisecond,
+				ProducerCompression: "zstd",
+				ProducerAcks:        ProducerAcksAll,
+				ProducerMaxInFlight: 5,
+				RetryBase:           100 * time.Millisecond,
+				RetryMax:            time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Invalid producer compression",
+			args: Config{
+				Address:             "localhost:1234",
+				Delay:               time.Second,
+				ConnTTL:             time.Second,
+				ProducerCompression: "example",
+				RetryBase:           100 * time.Millisecond,
+				RetryMax:            time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid producer acks",
+			args: Config{
+				Address:      "localhost:1234",
+				Delay:        time.Second,
+				ConnTTL:      time.Second,
+				ProducerAcks: "example",
+				RetryBase:    100 * time.Millisecond,
+				RetryMax:     time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with SASL and TLS",
+			args: Config{
+				Address:       "localhost:1234",
+				Delay:         time.Second,
+				ConnTTL:       time.Second,
+				SASLMechanism: SASLMechanismSCRAMSHA256,
+				SASLUsername:  "user",
+				SASLPassword:  "password",
+				TLSEnable:     true,
+				RetryBase:     100 * time.Millisecond,
+				RetryMax:      time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Invalid SASL mechanism",
+			args: Config{
+				Address:       "localhost:1234",
+				Delay:         time.Second,
+				ConnTTL:       time.Second,
+				SASLMechanism: "example",
+				SASLUsername:  "user",
+				SASLPassword:  "password",
+				RetryBase:     100 * time.Millisecond,
+				RetryMax:      time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Missing SASL username",
+			args: Config{
+				Address:       "localhost:1234",
+				Delay:         time.Second,
+				ConnTTL:       time.Second,
+				SASLMechanism: SASLMechanismPlain,
+				SASLPassword:  "password",
+				RetryBase:     100 * time.Millisecond,
+				RetryMax:      time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "TLS certificate without key",
+			args: Config{
+				Address:     "localhost:1234",
+				Delay:       time.Second,
+				ConnTTL:     time.Second,
+				TLSCertFile: "cert.pem",
+				RetryBase:   100 * time.Millisecond,
+				RetryMax:    time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with retry topics",
+			args: Config{
+				Address:             "localhost:1234",
+				Delay:               time.Second,
+				ConnTTL:             time.Second,
+				RetryTopicDelayList: []time.Duration{time.Second, time.Minute},
+				DeadLetter:          true,
+				RetryBase:           100 * time.Millisecond,
+				RetryMax:            time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Invalid retry topic delay",
+			args: Config{
+				Address:             "localhost:1234",
+				Delay:               time.Second,
+				ConnTTL:             time.Second,
+				RetryTopicDelayList: []time.Duration{time.Second, 0},
+				RetryBase:           100 * time.Millisecond,
+				RetryMax:            time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with topic settings",
+			args: Config{
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				TopicList: []string{"a:request::3:2:24h:compact", "b:response:json:::1h"},
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Invalid topic partitions",
+			args: Config{
+				Address:   "localhost:1234",
+				Delay:     time.Second,
+				ConnTTL:   time.Second,
+				TopicList: []string{"a:request:gob:example"},
+				RetryBase: 100 * time.Millisecond,
+				RetryMax:  time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid topic cleanup policy",
+			args: Config{
+				Address:            "localhost:1234",
+				Delay:              time.Second,
+				ConnTTL:            time.Second,
+				TopicCleanupPolicy: "example",
+				RetryBase:          100 * time.Millisecond,
+				RetryMax:           time.Second,
 			},
 			wantError: true,
 		},
@@ -64,3 +415,99 @@ func TestConfig_validate(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_TopicSpecs(t *testing.T) {
+	result, err := (&Config{
+		TopicList:          []string{"a:request", "b:response:json:3::1h"},
+		TopicPartitions:    2,
+		TopicCleanupPolicy: CleanupPolicyDelete,
+	}).TopicSpecs()
+	require.NoError(t, err)
+	assert.Equal(t, []TopicSpec{
+		{Name: "a", Message: "request", Codec: CodecGob, Partitions: 2, ReplicationFactor: 1, CleanupPolicy: CleanupPolicyDelete},
+		{Name: "b", Message: "response", Codec: CodecJSON, Partitions: 3, ReplicationFactor: 1, Retention: time.Hour, CleanupPolicy: CleanupPolicyDelete},
+	}, result)
+}
+
+func TestConfig_SaramaConfig(t *testing.T) {
+	testCaseList := []struct {
+		name         string
+		args         Config
+		wantStrategy string
+	}{
+		{
+			name:         "Default",
+			args:         Config{},
+			wantStrategy: sarama.RangeBalanceStrategyName,
+		},
+		{
+			name:         "Round robin",
+			args:         Config{RebalanceStrategy: RebalanceStrategyRoundRobin},
+			wantStrategy: sarama.RoundRobinBalanceStrategyName,
+		},
+		{
+			name:         "Sticky",
+			args:         Config{RebalanceStrategy: RebalanceStrategySticky},
+			wantStrategy: sarama.StickyBalanceStrategyName,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := tc.args.SaramaConfig()
+
+			require.NoError(t, err)
+			require.Len(t, result.Consumer.Group.Rebalance.GroupStrategies, 1)
+			assert.Equal(t, tc.wantStrategy, result.Consumer.Group.Rebalance.GroupStrategies[0].Name())
+			assert.True(t, result.Producer.Return.Successes)
+			assert.True(t, result.Consumer.Offsets.AutoCommit.Enable)
+			assert.Equal(t, sarama.OffsetNewest, result.Consumer.Offsets.Initial)
+		})
+	}
+}
+
+func TestConfig_SaramaConfig_offsets(t *testing.T) {
+	result, err := (&Config{
+		OffsetCommit:  OffsetCommitManual,
+		OffsetInitial: OffsetInitialOldest,
+	}).SaramaConfig()
+
+	require.NoError(t, err)
+	assert.False(t, result.Consumer.Offsets.AutoCommit.Enable)
+	assert.Equal(t, sarama.OffsetOldest, result.Consumer.Offsets.Initial)
+}
+
+func TestConfig_SaramaConfig_producer(t *testing.T) {
+	result, err := (&Config{ProducerIdempotent: true}).SaramaConfig()
+
+	require.NoError(t, err)
+	assert.True(t, result.Producer.Idempotent)
+	assert.Equal(t, sarama.WaitForAll, result.Producer.RequiredAcks)
+	assert.Equal(t, sarama.ReadUncommitted, result.Consumer.IsolationLevel)
+
+	result, err = (&Config{
+		ProducerBatchSize:   100,
+		ProducerLinger:      10 * time.Millisecond,
+		ProducerCompression: "lz4",
+		ProducerAcks:        ProducerAcksNone,
+		ProducerMaxInFlight: 3,
+	}).SaramaConfig()
+
+	require.NoError(t, err)
+	assert.Equal(t, 100, result.Producer.Flush.Messages)
+	assert.Equal(t, 10*time.Millisecond, result.Producer.Flush.Frequency)
+	assert.Equal(t, sarama.CompressionLZ4, result.Producer.Compression)
+	assert.Equal(t, sarama.NoResponse, result.Producer.RequiredAcks)
+	assert.Equal(t, 3, result.Net.MaxOpenRequests)
+
+	_, err = (&Config{ProducerIdempotent: true, ProducerAcks: ProducerAcksLeader}).SaramaConfig()
+
+	assert.Error(t, err)
+
+	result, err = (&Config{TransactionalID: "example"}).TransactionalSaramaConfig()
+
+	require.NoError(t, err)
+	assert.True(t, result.Producer.Idempotent)
+	assert.Equal(t, "example", result.Producer.Transaction.ID)
+	assert.Equal(t, sarama.ReadCommitted, result.Consumer.IsolationLevel)
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+	"github.com/xdg-go/scram"
+)
+
+const (
+	SASLMechanismPlain       = sarama.SASLTypePlaintext
+	SASLMechanismSCRAMSHA256 = sarama.SASLTypeSCRAMSHA256
+	SASLMechanismSCRAMSHA512 = sarama.SASLTypeSCRAMSHA512
+)
+
+// secretMask replaces set credentials in formatted config
+const secretMask = "***"
+
+// String formats config for logging with SASL credentials masked
+func (c Config) String() string {
+	// config has no String method, so formatting it doesn't recurse
+	type config Config
+	masked := config(c)
+	for _, secret := range []*string{&masked.SASLUsername, &masked.SASLPassword} {
+		if *secret != "" {
+			*secret = secretMask
+		}
+	}
+
+	return fmt.Sprintf("%+v", masked)
+}
+
+// resolveSecrets reads secrets passed as files, e.g. mounted docker or kubernetes secrets
+func (c *Config) resolveSecrets() error {
+	for _, secret := range []struct {
+		name  string
+		value *string
+		path  string
+	}{
+		{name: "KAFKA_SASL_USERNAME", value: &c.SASLUsername, path: c.SASLUsernameFile},
+		{name: "KAFKA_SASL_PASSWORD", value: &c.SASLPassword, path: c.SASLPasswordFile},
+	} {
+		if secret.path == "" {
+			continue
+		}
+		if *secret.value != "" {
+			return errors.Errorf("only one of %s and %s_FILE can be set", secret.name, secret.name)
+		}
+
+		data, err := os.ReadFile(secret.path)
+		if err != nil {
+			return errors.Wrapf(err, "reading %s_FILE", secret.name)
+		}
+		*secret.value = strings.TrimRight(string(data), "\r\n")
+	}
+
+	return nil
+}
+
+// TLSConfig returns TLS config of broker connections, nil when TLS is disabled
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	if !c.TLSEnable && c.TLSCAFile == "" && c.TLSCertFile == "" {
+		return nil, nil
+	}
+
+	result := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.TLSServerName,
+		InsecureSkipVerify: c.TLSInsecureSkipVerify,
+	}
+	if c.TLSCAFile != "" {
+		data, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading CA file")
+		}
+		result.RootCAs = x509.NewCertPool()
+		if !result.RootCAs.AppendCertsFromPEM(data) {
+			return nil, errors.Errorf("no certificates in CA file (%s)", c.TLSCAFile)
+		}
+	}
+	if c.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading client certificate")
+		}
+		result.Certificates = []tls.Certificate{cert}
+	}
+
+	return result, nil
+}
+
+// applySecurity configures TLS and SASL of broker connections
+func (c *Config) applySecurity(cfg *sarama.Config) error {
+	tlsConfig, err := c.TLSConfig()
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		cfg.Net.TLS.Enable = true
+		cfg.Net.TLS.Config = tlsConfig
+	}
+
+	if c.SASLMechanism == "" {
+		return nil
+	}
+	cfg.Net.SASL.Enable = true
+	cfg.Net.SASL.Handshake = true
+	cfg.Net.SASL.Mechanism = sarama.SASLMechanism(c.SASLMechanism)
+	cfg.Net.SASL.User = c.SASLUsername
+	cfg.Net.SASL.Password = c.SASLPassword
+	switch c.SASLMechanism {
+	case SASLMechanismSCRAMSHA256:
+		cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: scram.SHA256}
+		}
+	case SASLMechanismSCRAMSHA512:
+		cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: scram.SHA512}
+		}
+	}
+
+	return nil
+}
+
+// scramClient adapts SCRAM conversation to sarama
+type scramClient struct {
+	hashGenerator scram.HashGeneratorFcn
+	conversation  *scram.ClientConversation
+}
+
+func (s *scramClient) Begin(userName, password, authzID string) error {
+	client, err := s.hashGenerator.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	s.conversation = client.NewConversation()
+
+	return nil
+}
+
+func (s *scramClient) Step(challenge string) (string, error) {
+	return s.conversation.Step(challenge)
+}
+
+func (s *scramClient) Done() bool {
+	return s.conversation.Done()
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xdg-go/scram"
+)
+
+// writeCertificate writes self-signed certificate and its key, returns their paths
+func writeCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "example"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certPath, keyPath
+}
+
+func TestConfig_resolveSecrets(t *testing.T) {
+	passwordPath := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordPath, []byte("example\n"), 0o600))
+
+	testCaseList := []struct {
+		name         string
+		args         Config
+		wantPassword string
+		wantError    bool
+	}{
+		{
+			name:         "Password from file",
+			args:         Config{SASLPasswordFile: passwordPath},
+			wantPassword: "example",
+		},
+		{
+			name:         "Password from env",
+			args:         Config{SASLPassword: "example"},
+			wantPassword: "example",
+		},
+		{
+			name:      "Both password and file",
+			args:      Config{SASLPassword: "example", SASLPasswordFile: passwordPath},
+			wantError: true,
+		},
+		{
+			name:      "Missing file",
+			args:      Config{SASLUsernameFile: filepath.Join(t.TempDir(), "example")},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.args.resolveSecrets()
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantPassword, tc.args.SASLPassword)
+		})
+	}
+}
+
+func TestConfig_String(t *testing.T) {
+	passwordPath := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordPath, []byte("secret-password\n"), 0o600))
+
+	cfg := Config{
+		Address:          "localhost:9092",
+		SASLMechanism:    SASLMechanismPlain,
+		SASLUsername:     "secret-user",
+		SASLPasswordFile: passwordPath,
+	}
+	require.NoError(t, cfg.resolveSecrets())
+	require.Equal(t, "secret-password", cfg.SASLPassword)
+
+	for _, result := range []string{cfg.String(), fmt.Sprintf("%+v", cfg), fmt.Sprintf("%v", &cfg)} {
+		assert.NotContains(t, result, "secret-password")
+		assert.NotContains(t, result, "secret-user")
+		assert.Equal(t, 2, strings.Count(result, secretMask))
+		assert.Contains(t, result, "localhost:9092")
+	}
+	assert.Equal(t, "secret-password", cfg.SASLPassword)
+}
+
+func TestConfig_TLSConfig(t *testing.T) {
+	certPath, keyPath := writeCertificate(t)
+
+	testCaseList := []struct {
+		name      string
+		args      Config
+		wantTLS   bool
+		wantError bool
+	}{
+		{
+			name: "Disabled",
+			args: Config{},
+		},
+		{
+			name:    "System CA",
+			args:    Config{TLSEnable: true},
+			wantTLS: true,
+		},
+		{
+			name:    "CA and client certificate",
+			args:    Config{TLSCAFile: certPath, TLSCertFile: certPath, TLSKeyFile: keyPath},
+			wantTLS: true,
+		},
+		{
+			name:      "Invalid CA file",
+			args:      Config{TLSCAFile: keyPath},
+			wantError: true,
+		},
+		{
+			name:      "Missing client key",
+			args:      Config{TLSCertFile: certPath, TLSKeyFile: filepath.Join(t.TempDir(), "example")},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := tc.args.SaramaConfig()
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantTLS, result.Net.TLS.Enable)
+			assert.Equal(t, tc.wantTLS, result.Net.TLS.Config != nil)
+		})
+	}
+}
+
+func TestConfig_SaramaConfig_sasl(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      Config
+		wantSCRAM bool
+		wantError bool
+	}{
+		{
+			name:      "Plain",
+			args:      Config{SASLMechanism: SASLMechanismPlain, SASLUsername: "user", SASLPassword: "password"},
+			wantSCRAM: false,
+		},
+		{
+			name:      "SCRAM-SHA-512",
+			args:      Config{SASLMechanism: SASLMechanismSCRAMSHA512, SASLUsername: "user", SASLPassword: "password"},
+			wantSCRAM: true,
+		},
+		{
+			name:      "Missing password",
+			args:      Config{SASLMechanism: SASLMechanismPlain, SASLUsername: "user"},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := tc.args.SaramaConfig()
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, result.Net.SASL.Enable)
+			assert.Equal(t, sarama.SASLMechanism(tc.args.SASLMechanism), result.Net.SASL.Mechanism)
+			assert.Equal(t, tc.wantSCRAM, result.Net.SASL.SCRAMClientGeneratorFunc != nil)
+		})
+	}
+}
+
+func TestScramClient(t *testing.T) {
+	client, err := scram.SHA256.NewClient("user", "password", "")
+	require.NoError(t, err)
+	credential := client.GetStoredCredentials(scram.KeyFactors{Salt: "salt", Iters: 4096})
+	server, err := scram.SHA256.NewServer(func(string) (scram.StoredCredentials, error) {
+		return credential, nil
+	})
+	require.NoError(t, err)
+	serverConversation := server.NewConversation()
+
+	result, err := (&Config{
+		SASLMechanism: SASLMechanismSCRAMSHA256,
+		SASLUsername:  "user",
+		SASLPassword:  "password",
+	}).SaramaConfig()
+	require.NoError(t, err)
+	s := result.Net.SASL.SCRAMClientGeneratorFunc()
+	require.NoError(t, s.Begin("user", "password", ""))
+
+	challenge := ""
+	for !s.Done() {
+		response, err := s.Step(challenge)
+		require.NoError(t, err)
+		if s.Done() {
+			break
+		}
+		challenge, err = serverConversation.Step(response)
+		require.NoError(t, err)
+	}
+
+	assert.True(t, serverConversation.Valid())
+}
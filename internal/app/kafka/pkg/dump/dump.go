@@ -0,0 +1,261 @@
+// Package dump writes Kafka topic messages to JSON lines and replays them back, e.g. to reproduce incidents locally
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/header"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/producer"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/retry"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/topic"
+	"github.com/kirill-a-belov/test_task_framework/pkg/backoff"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+// idleTimeout ends partition dump when no message arrives before range end,
+// offsets of transaction markers and compacted messages have no messages
+var idleTimeout = 5 * time.Second
+
+// Header is record header, value is kept as bytes like message value, so binary headers of other producers survive
+type Header struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Record is dumped message, one record per line
+type Record struct {
+	Topic     string    `json:"topic"`
+	Partition int32     `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Timestamp time.Time `json:"timestamp"`
+	Key       []byte    `json:"key,omitempty"`
+	Headers   []Header  `json:"headers,omitempty"`
+	Value     []byte    `json:"value"`
+	// Decoded is value decoded with codec of topic for reading, raw value is replayed
+	Decoded     any    `json:"decoded,omitempty"`
+	DecodeError string `json:"decode_error,omitempty"`
+}
+
+// Range selects messages of every partition, offsets and timestamps can be combined,
+// zero values mean partition start and end, end offset and timestamp are exclusive
+type Range struct {
+	FromOffset int64
+	ToOffset   int64
+	FromTime   time.Time
+	ToTime     time.Time
+}
+
+// Dump writes messages of topic range to w partition by partition, values are decoded with codec
+// of topic message was originally produced to, returns number of dumped messages
+func Dump(
+	ctx context.Context,
+	client sarama.Client,
+	registry *topic.Registry,
+	w io.Writer,
+	name string,
+	r Range,
+) (int, error) {
+	ctx, span := tracer.Start(ctx, "internal.app.kafka.pkg.dump.Dump")
+	defer span.End()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return 0, errors.Wrap(err, "creating consumer")
+	}
+	defer func() {
+		_ = consumer.Close()
+	}()
+
+	partitionList, err := client.Partitions(name)
+	if err != nil {
+		return 0, errors.Wrap(err, "listing partitions")
+	}
+
+	encoder := json.NewEncoder(w)
+	dumpedCnt := 0
+	for _, partition := range partitionList {
+		cnt, err := dumpPartition(ctx, client, consumer, registry, encoder, name, partition, r)
+		dumpedCnt += cnt
+		if err != nil {
+			return dumpedCnt, errors.Wrapf(err, "dumping partition (%d)", partition)
+		}
+	}
+
+	return dumpedCnt, nil
+}
+
+func dumpPartition(
+	ctx context.Context,
+	client sarama.Client,
+	consumer sarama.Consumer,
+	registry *topic.Registry,
+	encoder *json.Encoder,
+	name string,
+	partition int32,
+	r Range,
+) (int, error) {
+	start, end, err := r.bounds(client, name, partition)
+	if err != nil {
+		return 0, err
+	}
+	if start >= end {
+		return 0, nil
+	}
+
+	pc, err := consumer.ConsumePartition(name, partition, start)
+	if err != nil {
+		return 0, errors.Wrap(err, "consuming partition")
+	}
+	defer func() {
+		_ = pc.Close()
+	}()
+
+	dumpedCnt := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return dumpedCnt, ctx.Err()
+		case err := <-pc.Errors():
+			return dumpedCnt, err
+		case <-time.After(idleTimeout):
+			return dumpedCnt, nil
+		case msg := <-pc.Messages():
+			if msg.Offset >= end {
+				return dumpedCnt, nil
+			}
+			if err := encoder.Encode(newRecord(registry, msg)); err != nil {
+				return dumpedCnt, errors.Wrapf(err, "writing record (%d)", msg.Offset)
+			}
+			dumpedCnt++
+			if msg.Offset >= end-1 {
+				return dumpedCnt, nil
+			}
+		}
+	}
+}
+
+// bounds resolves range to partition offsets, end is exclusive
+func (r Range) bounds(client sarama.Client, name string, partition int32) (int64, int64, error) {
+	oldest, err := client.GetOffset(name, partition, sarama.OffsetOldest)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "fetching oldest offset")
+	}
+	newest, err := client.GetOffset(name, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "fetching high water mark")
+	}
+
+	start, end := max(r.FromOffset, oldest), newest
+	if r.ToOffset > 0 {
+		end = min(end, r.ToOffset)
+	}
+	if !r.FromTime.IsZero() {
+		offset, err := client.GetOffset(name, partition, r.FromTime.UnixMilli())
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "fetching offset of start time")
+		}
+		// no message produced after start time
+		if offset < 0 {
+			offset = newest
+		}
+		start = max(start, offset)
+	}
+	if !r.ToTime.IsZero() {
+		offset, err := client.GetOffset(name, partition, r.ToTime.UnixMilli())
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "fetching offset of end time")
+		}
+		if offset >= 0 {
+			end = min(end, offset)
+		}
+	}
+
+	return start, end, nil
+}
+
+func newRecord(registry *topic.Registry, msg *sarama.ConsumerMessage) Record {
+	result := Record{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Timestamp: msg.Timestamp,
+		Key:       msg.Key,
+		Value:     msg.Value,
+	}
+	for _, h := range msg.Headers {
+		if h != nil {
+			result.Headers = append(result.Headers, Header{Key: string(h.Key), Value: h.Value})
+		}
+	}
+
+	decoded, err := registry.DecodeMessage(
+		retry.OriginalTopic(msg),
+		header.Get(msg.Headers, header.MessageType),
+		header.Get(msg.Headers, header.ContentType),
+		msg.Value,
+	)
+	if err != nil {
+		result.DecodeError = err.Error()
+	} else {
+		result.Decoded = decoded
+	}
+
+	return result
+}
+
+// Replay produces records read from r to name topic, or to their own topics when name is empty,
+// rate limits number of messages per second when positive, returns number of replayed messages
+func Replay(ctx context.Context, r io.Reader, producer producer.Producer, name string, rate float64) (int, error) {
+	ctx, span := tracer.Start(ctx, "internal.app.kafka.pkg.dump.Replay")
+	defer span.End()
+
+	var interval time.Duration
+	if rate > 0 {
+		interval = time.Duration(float64(time.Second) / rate)
+	}
+
+	decoder := json.NewDecoder(r)
+	replayedCnt := 0
+	for {
+		var record Record
+		if err := decoder.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				return replayedCnt, nil
+			}
+
+			return replayedCnt, errors.Wrapf(err, "reading record (%d)", replayedCnt+1)
+		}
+
+		if replayedCnt > 0 && interval > 0 {
+			if err := backoff.Wait(ctx, interval); err != nil {
+				return replayedCnt, err
+			}
+		} else if err := ctx.Err(); err != nil {
+			return replayedCnt, err
+		}
+
+		msg := &sarama.ProducerMessage{
+			Topic: record.Topic,
+			Value: sarama.ByteEncoder(record.Value),
+		}
+		if name != "" {
+			msg.Topic = name
+		}
+		if record.Key != nil {
+			msg.Key = sarama.ByteEncoder(record.Key)
+		}
+		for _, h := range record.Headers {
+			msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(h.Key), Value: h.Value})
+		}
+		if _, _, err := producer.SendMessage(msg); err != nil {
+			return replayedCnt, errors.Wrapf(err, "producing record (%s/%d/%d)", record.Topic, record.Partition, record.Offset)
+		}
+		replayedCnt++
+	}
+}
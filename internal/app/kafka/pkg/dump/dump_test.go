@@ -0,0 +1,157 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/header"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/topic"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+)
+
+const exampleTopic = "example_topic"
+
+type producerStub struct {
+	sarama.SyncProducer
+	sentList []*sarama.ProducerMessage
+}
+
+func (p *producerStub) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	p.sentList = append(p.sentList, msg)
+
+	return 0, 0, nil
+}
+
+func TestDump(t *testing.T) {
+	value := &bytes.Buffer{}
+	require.NoError(t, gob.NewEncoder(value).Encode(protocol.Request{
+		Message: protocol.Message{
+			Type: protocol.MessageTypeRequest,
+		},
+		Payload: []int64{1, 2, 3},
+	}))
+
+	// mock fetch response can't carry headers, records are built by hand
+	fetchResponse := &sarama.FetchResponse{Version: 8}
+	for offset := int64(0); offset < 4; offset++ {
+		fetchResponse.AddRecord(exampleTopic, 0, sarama.StringEncoder("key"), sarama.ByteEncoder(value.Bytes()), offset)
+	}
+	block := fetchResponse.GetBlock(exampleTopic, 0)
+	block.HighWaterMarkOffset = 4
+	for _, record := range block.RecordsSet[0].RecordBatch.Records {
+		record.Headers = []*sarama.RecordHeader{
+			{Key: []byte(header.MessageType), Value: []byte(topic.MessageRequest)},
+			{Key: []byte(header.CorrelationID), Value: []byte("id")},
+			{Key: []byte("binary"), Value: []byte{0xff, 0x00, 0xfe}},
+		}
+	}
+
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(exampleTopic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset(exampleTopic, 0, sarama.OffsetOldest, 0).
+			SetOffset(exampleTopic, 0, sarama.OffsetNewest, 4),
+		"FetchRequest": sarama.NewMockWrapper(fetchResponse),
+	})
+
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V2_0_0_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, cfg)
+	require.NoError(t, err)
+	defer func() {
+		_ = client.Close()
+	}()
+	registry, err := topic.New([]config.TopicSpec{
+		{Name: exampleTopic, Message: topic.MessageRequest, Codec: config.CodecGob},
+	})
+	require.NoError(t, err)
+
+	output := &bytes.Buffer{}
+	dumpedCnt, err := Dump(context.Background(), client, registry, output, exampleTopic, Range{FromOffset: 1, ToOffset: 3})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, dumpedCnt)
+	lineList := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lineList, 2)
+	var record Record
+	require.NoError(t, json.Unmarshal([]byte(lineList[0]), &record))
+	assert.Equal(t, int64(1), record.Offset)
+	assert.Equal(t, []byte("key"), record.Key)
+	assert.Equal(t, value.Bytes(), record.Value)
+	assert.Contains(t, record.Headers, Header{Key: header.CorrelationID, Value: []byte("id")})
+	assert.Contains(t, record.Headers, Header{Key: "binary", Value: []byte{0xff, 0x00, 0xfe}})
+	assert.Empty(t, record.DecodeError)
+	require.IsType(t, map[string]any{}, record.Decoded)
+	assert.Equal(t, []any{float64(1), float64(2), float64(3)}, record.Decoded.(map[string]any)["Payload"])
+}
+
+func TestReplay(t *testing.T) {
+	input := strings.Join([]string{
+		`{"topic":"example_topic","offset":1,"key":"a2V5","headers":[{"key":"correlation_id","value":"aWQ="},{"key":"binary","value":"/wD+"}],"value":"dmFsdWU="}`,
+		`{"topic":"example_topic","offset":2,"value":"dmFsdWU="}`,
+	}, "\n")
+
+	testCaseList := []struct {
+		name         string
+		args         string
+		rate         float64
+		wantTopic    string
+		wantDuration time.Duration
+	}{
+		{
+			name:      "Original topic",
+			wantTopic: exampleTopic,
+		},
+		{
+			name:         "Rewritten topic with rate limit",
+			args:         "example",
+			rate:         10,
+			wantTopic:    "example",
+			wantDuration: 100 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			producer := &producerStub{}
+			startedAt := time.Now()
+
+			replayedCnt, err := Replay(context.Background(), strings.NewReader(input), producer, tc.args, tc.rate)
+
+			require.NoError(t, err)
+			assert.Equal(t, 2, replayedCnt)
+			assert.GreaterOrEqual(t, time.Since(startedAt), tc.wantDuration)
+			require.Len(t, producer.sentList, 2)
+			for _, sent := range producer.sentList {
+				assert.Equal(t, tc.wantTopic, sent.Topic)
+			}
+			assert.Equal(t, sarama.ByteEncoder("key"), producer.sentList[0].Key)
+			assert.Nil(t, producer.sentList[1].Key)
+			assert.Equal(t, []sarama.RecordHeader{
+				{Key: []byte("correlation_id"), Value: []byte("id")},
+				{Key: []byte("binary"), Value: []byte{0xff, 0x00, 0xfe}},
+			}, producer.sentList[0].Headers)
+		})
+	}
+
+	t.Run("Invalid record", func(t *testing.T) {
+		replayedCnt, err := Replay(context.Background(), strings.NewReader("{"), &producerStub{}, "", 0)
+
+		assert.Error(t, err)
+		assert.Equal(t, 0, replayedCnt)
+	})
+}
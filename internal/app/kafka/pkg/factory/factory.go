@@ -0,0 +1,29 @@
+// Package factory creates sarama clients used by Kafka service, tests replace them with mocks
+package factory
+
+import "github.com/IBM/sarama"
+
+// Factory holds constructors of sarama clients
+type Factory struct {
+	Client        func(addressList []string, config *sarama.Config) (sarama.Client, error)
+	ClusterAdmin  func(client sarama.Client) (sarama.ClusterAdmin, error)
+	SyncProducer  func(client sarama.Client) (sarama.SyncProducer, error)
+	AsyncProducer func(client sarama.Client) (sarama.AsyncProducer, error)
+	// TxnProducer creates transactional producer, it has its own connections since config differs
+	TxnProducer   func(addressList []string, config *sarama.Config) (sarama.SyncProducer, error)
+	Consumer      func(client sarama.Client) (sarama.Consumer, error)
+	ConsumerGroup func(groupID string, client sarama.Client) (sarama.ConsumerGroup, error)
+}
+
+// Default returns factory of real sarama clients
+func Default() Factory {
+	return Factory{
+		Client:        sarama.NewClient,
+		ClusterAdmin:  sarama.NewClusterAdminFromClient,
+		SyncProducer:  sarama.NewSyncProducerFromClient,
+		AsyncProducer: sarama.NewAsyncProducerFromClient,
+		TxnProducer:   sarama.NewSyncProducer,
+		Consumer:      sarama.NewConsumerFromClient,
+		ConsumerGroup: sarama.NewConsumerGroupFromClient,
+	}
+}
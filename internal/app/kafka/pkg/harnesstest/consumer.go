@@ -0,0 +1,276 @@
+package harnesstest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+// consumer registers partition consumers in harness, messages are not yielded to closed ones
+type consumer struct {
+	*mocks.Consumer
+	harness *Harness
+}
+
+func (c *consumer) ConsumePartition(topic string, partition int32, offset int64) (sarama.PartitionConsumer, error) {
+	pc, err := c.Consumer.ConsumePartition(topic, partition, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &partitionConsumer{PartitionConsumer: pc.(*mocks.PartitionConsumer), harness: c.harness}
+	c.harness.mu.Lock()
+	c.harness.partitionConsumerList[topic] = result
+	c.harness.mu.Unlock()
+
+	return result, nil
+}
+
+func (c *consumer) Close() error {
+	c.harness.mu.Lock()
+	for _, pc := range c.harness.partitionConsumerList {
+		pc.closed = true
+	}
+	c.harness.mu.Unlock()
+
+	return c.Consumer.Close()
+}
+
+type partitionConsumer struct {
+	*mocks.PartitionConsumer
+	harness *Harness
+	closed  bool
+}
+
+func (pc *partitionConsumer) AsyncClose() {
+	pc.harness.mu.Lock()
+	pc.closed = true
+	pc.harness.mu.Unlock()
+
+	pc.PartitionConsumer.AsyncClose()
+}
+
+func (pc *partitionConsumer) Close() error {
+	pc.harness.mu.Lock()
+	pc.closed = true
+	pc.harness.mu.Unlock()
+
+	return pc.PartitionConsumer.Close()
+}
+
+func newConsumerGroup(h *Harness) *consumerGroup {
+	return &consumerGroup{
+		harness:   h,
+		errorChan: make(chan error, sarama.NewConfig().ChannelBufferSize),
+	}
+}
+
+// consumerGroup is the only member of its group, it claims partition 0 of every topic
+type consumerGroup struct {
+	harness   *Harness
+	errorChan chan error
+
+	mu           sync.Mutex
+	closed       bool
+	generationID int32
+	cancel       context.CancelFunc
+}
+
+// Consume runs single session like sarama does, session ends when context is done or any claim handler returns
+func (g *consumerGroup) Consume(ctx context.Context, topicList []string, handler sarama.ConsumerGroupHandler) error {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+
+		return sarama.ErrClosedConsumerGroup
+	}
+	if err := g.harness.consumeError(); err != nil {
+		g.mu.Unlock()
+
+		return err
+	}
+	g.generationID++
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	g.cancel = cancel
+	s := &session{
+		ctx:          ctx,
+		harness:      g.harness,
+		memberID:     fmt.Sprintf("harness-%d", g.generationID),
+		generationID: g.generationID,
+		claimList:    make(map[string][]int32, len(topicList)),
+	}
+	g.mu.Unlock()
+
+	for _, topic := range topicList {
+		s.claimList[topic] = []int32{0}
+	}
+	if err := handler.Setup(s); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, topic := range topicList {
+		c := newClaim(ctx, g.harness, topic)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cancel()
+
+			if err := handler.ConsumeClaim(s, c); err != nil {
+				g.handleError(err)
+			}
+		}()
+	}
+	<-ctx.Done()
+	wg.Wait()
+
+	return handler.Cleanup(s)
+}
+
+func (g *consumerGroup) handleError(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return
+	}
+	select {
+	case g.errorChan <- err:
+	default:
+	}
+}
+
+func (g *consumerGroup) Errors() <-chan error {
+	return g.errorChan
+}
+
+func (g *consumerGroup) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	if g.cancel != nil {
+		g.cancel()
+	}
+	close(g.errorChan)
+
+	return nil
+}
+
+func (g *consumerGroup) Pause(map[string][]int32) {}
+
+func (g *consumerGroup) Resume(map[string][]int32) {}
+
+func (g *consumerGroup) PauseAll() {}
+
+func (g *consumerGroup) ResumeAll() {}
+
+// session marks offsets in harness immediately, so commit is no-op
+type session struct {
+	ctx          context.Context
+	harness      *Harness
+	memberID     string
+	generationID int32
+	claimList    map[string][]int32
+}
+
+func (s *session) Claims() map[string][]int32 {
+	return s.claimList
+}
+
+func (s *session) MemberID() string {
+	return s.memberID
+}
+
+func (s *session) GenerationID() int32 {
+	return s.generationID
+}
+
+func (s *session) MarkOffset(topic string, _ int32, offset int64, _ string) {
+	s.harness.mark(topic, offset, false)
+}
+
+func (s *session) Commit() {}
+
+func (s *session) ResetOffset(topic string, _ int32, offset int64, _ string) {
+	s.harness.mark(topic, offset, true)
+}
+
+func (s *session) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+func (s *session) Context() context.Context {
+	return s.ctx
+}
+
+// newClaim reads topic log from marked offset, so messages left unmarked by previous session are redelivered
+func newClaim(ctx context.Context, h *Harness, topic string) *claim {
+	c := &claim{
+		harness:       h,
+		topic:         topic,
+		initialOffset: h.Marked(topic),
+		messageChan:   make(chan *sarama.ConsumerMessage),
+	}
+	go func() {
+		defer close(c.messageChan)
+
+		for offset := c.initialOffset; ; {
+			msg, notifyChan := h.message(topic, offset)
+			if msg == nil {
+				select {
+				case <-notifyChan:
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case c.messageChan <- msg:
+				offset++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return c
+}
+
+type claim struct {
+	harness       *Harness
+	topic         string
+	initialOffset int64
+	messageChan   chan *sarama.ConsumerMessage
+}
+
+func (c *claim) Topic() string {
+	return c.topic
+}
+
+func (c *claim) Partition() int32 {
+	return 0
+}
+
+func (c *claim) InitialOffset() int64 {
+	return c.initialOffset
+}
+
+func (c *claim) HighWaterMarkOffset() int64 {
+	c.harness.mu.Lock()
+	defer c.harness.mu.Unlock()
+
+	return int64(len(c.harness.logList[c.topic]))
+}
+
+func (c *claim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messageChan
+}
@@ -0,0 +1,301 @@
+// Package harnesstest runs Kafka service against in-memory sarama clients in tests, cluster metadata and offsets
+// are served by sarama mock broker, produced messages are recorded and looped back to consumers
+package harnesstest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/factory"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/retry"
+)
+
+// Harness holds single partition log of every topic, consumer group reads it from marked offset
+// and rpc partition consumer receives messages appended after it is started
+type Harness struct {
+	// Broker serves client and cluster admin requests, handlers can be replaced by SetHandler
+	Broker *sarama.MockBroker
+
+	consumer *mocks.Consumer
+
+	mu                    sync.Mutex
+	handlerList           map[string]sarama.MockResponse
+	logList               map[string][]*sarama.ConsumerMessage
+	producedList          map[string][]*sarama.ProducerMessage
+	markedList            map[string]int64
+	partitionConsumerList map[string]*partitionConsumer
+	notifyChan            chan struct{}
+	produceErr            error
+	consumeErrList        []error
+}
+
+// New starts mock broker serving metadata and offsets of configured topics and their retry topics
+func New(t *testing.T, cfg *config.Config) *Harness {
+	topicSpecList, err := cfg.TopicSpecs()
+	require.NoError(t, err)
+
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	h := &Harness{
+		Broker: broker,
+
+		consumer: mocks.NewConsumer(t, nil),
+
+		logList:               make(map[string][]*sarama.ConsumerMessage),
+		producedList:          make(map[string][]*sarama.ProducerMessage),
+		markedList:            make(map[string]int64),
+		partitionConsumerList: make(map[string]*partitionConsumer),
+		notifyChan:            make(chan struct{}),
+	}
+
+	metadata := sarama.NewMockMetadataResponse(t).
+		SetBroker(broker.Addr(), broker.BrokerID()).
+		SetController(broker.BrokerID())
+	offset := sarama.NewMockOffsetResponse(t)
+	offsetFetch := sarama.NewMockOffsetFetchResponse(t)
+	topicMetadata := make(map[string][]int32)
+	for _, spec := range topicSpecList {
+		topicList := []string{spec.Name, retry.DeadLetterTopic(spec.Name)}
+		for i := range cfg.RetryTopicDelayList {
+			topicList = append(topicList, retry.Topic(spec.Name, i+1))
+		}
+		for _, topic := range topicList {
+			metadata.SetLeader(topic, 0, broker.BrokerID())
+			offset.SetOffset(topic, 0, sarama.OffsetOldest, 0).SetOffset(topic, 0, sarama.OffsetNewest, 0)
+			offsetFetch.SetOffset(cfg.GroupID, topic, 0, -1, "", sarama.ErrNoError)
+			topicMetadata[topic] = []int32{0}
+		}
+	}
+	h.handlerList = map[string]sarama.MockResponse{
+		"MetadataRequest": metadata,
+		"OffsetRequest":   offset,
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, cfg.GroupID, broker),
+		"OffsetFetchRequest":     offsetFetch,
+		"DescribeConfigsRequest": sarama.NewMockDescribeConfigsResponse(t),
+		"CreateTopicsRequest":    sarama.NewMockCreateTopicsResponse(t),
+	}
+	broker.SetHandlerByMap(h.handlerList)
+
+	h.consumer.SetTopicMetadata(topicMetadata)
+	h.consumer.ExpectConsumePartition(cfg.ReplyTopic, 0, sarama.OffsetNewest)
+
+	return h
+}
+
+// Factory returns constructors of clients connected to harness, config of real clients is kept
+func (h *Harness) Factory() factory.Factory {
+	return factory.Factory{
+		Client: func(_ []string, cfg *sarama.Config) (sarama.Client, error) {
+			return sarama.NewClient([]string{h.Broker.Addr()}, cfg)
+		},
+		ClusterAdmin: sarama.NewClusterAdminFromClient,
+		SyncProducer: func(sarama.Client) (sarama.SyncProducer, error) {
+			return newSyncProducer(h, false), nil
+		},
+		AsyncProducer: func(sarama.Client) (sarama.AsyncProducer, error) {
+			return newAsyncProducer(h), nil
+		},
+		TxnProducer: func([]string, *sarama.Config) (sarama.SyncProducer, error) {
+			return newSyncProducer(h, true), nil
+		},
+		Consumer: func(sarama.Client) (sarama.Consumer, error) {
+			return &consumer{Consumer: h.consumer, harness: h}, nil
+		},
+		ConsumerGroup: func(string, sarama.Client) (sarama.ConsumerGroup, error) {
+			return newConsumerGroup(h), nil
+		},
+	}
+}
+
+// SetHandler replaces broker response of request type, e.g. to simulate broker errors
+func (h *Harness) SetHandler(requestType string, response sarama.MockResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.handlerList[requestType] = response
+	h.Broker.SetHandlerByMap(h.handlerList)
+}
+
+// FailProduce makes every produced message fail with err until it is called with nil
+func (h *Harness) FailProduce(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.produceErr = err
+}
+
+// FailConsume makes next consumer group sessions fail with errors of list, one error per session
+func (h *Harness) FailConsume(errList ...error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.consumeErrList = append(h.consumeErrList, errList...)
+}
+
+// Feed appends message to topic log as if it was produced by another service
+func (h *Harness) Feed(msg *sarama.ConsumerMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.append(msg)
+}
+
+// FeedError passes err to rpc partition consumer of topic
+func (h *Harness) FeedError(topic string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if pc, ok := h.partitionConsumerList[topic]; ok && !pc.closed {
+		pc.YieldError(err)
+	}
+}
+
+// Produced returns messages successfully produced to topic
+func (h *Harness) Produced(topic string) []*sarama.ProducerMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]*sarama.ProducerMessage(nil), h.producedList[topic]...)
+}
+
+// WaitProduced waits until at least n messages are produced to topic, returns false on timeout
+func (h *Harness) WaitProduced(topic string, n int, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		h.mu.Lock()
+		produced, notifyChan := len(h.producedList[topic]), h.notifyChan
+		h.mu.Unlock()
+		if produced >= n {
+			return true
+		}
+
+		select {
+		case <-notifyChan:
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+// Marked returns offset of the next message consumer group reads from topic
+func (h *Harness) Marked(topic string) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.markedList[topic]
+}
+
+// produce records message and appends it to topic log
+func (h *Harness) produce(msg *sarama.ProducerMessage) (int32, int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.produceErr != nil {
+		return 0, 0, h.produceErr
+	}
+
+	consumed, err := consumerMessage(msg)
+	if err != nil {
+		return 0, 0, err
+	}
+	h.append(consumed)
+	msg.Partition, msg.Offset, msg.Timestamp = consumed.Partition, consumed.Offset, consumed.Timestamp
+	h.producedList[msg.Topic] = append(h.producedList[msg.Topic], msg)
+
+	return msg.Partition, msg.Offset, nil
+}
+
+// append adds message to topic log and wakes up its readers, must be called under lock
+func (h *Harness) append(msg *sarama.ConsumerMessage) {
+	msg.Partition = 0
+	msg.Offset = int64(len(h.logList[msg.Topic]))
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	h.logList[msg.Topic] = append(h.logList[msg.Topic], msg)
+
+	if pc, ok := h.partitionConsumerList[msg.Topic]; ok && !pc.closed {
+		yielded := *msg
+		pc.YieldMessage(&yielded)
+	}
+
+	close(h.notifyChan)
+	h.notifyChan = make(chan struct{})
+}
+
+// message returns message of topic log at offset, or channel closed on next append when there is none
+func (h *Harness) message(topic string, offset int64) (*sarama.ConsumerMessage, <-chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if offset < int64(len(h.logList[topic])) {
+		return h.logList[topic][offset], nil
+	}
+
+	return nil, h.notifyChan
+}
+
+// mark moves consumer group offset of topic, only reset moves it back
+func (h *Harness) mark(topic string, offset int64, reset bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if reset || offset > h.markedList[topic] {
+		h.markedList[topic] = offset
+	}
+}
+
+func (h *Harness) produceError() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.produceErr
+}
+
+func (h *Harness) consumeError() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.consumeErrList) == 0 {
+		return nil
+	}
+	err := h.consumeErrList[0]
+	h.consumeErrList = h.consumeErrList[1:]
+
+	return err
+}
+
+func consumerMessage(msg *sarama.ProducerMessage) (*sarama.ConsumerMessage, error) {
+	result := &sarama.ConsumerMessage{
+		Topic:     msg.Topic,
+		Timestamp: msg.Timestamp,
+	}
+
+	var err error
+	if msg.Key != nil {
+		if result.Key, err = msg.Key.Encode(); err != nil {
+			return nil, err
+		}
+	}
+	if msg.Value != nil {
+		if result.Value, err = msg.Value.Encode(); err != nil {
+			return nil, err
+		}
+	}
+	for i := range msg.Headers {
+		h := msg.Headers[i]
+		result.Headers = append(result.Headers, &h)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,253 @@
+package harnesstest
+
+import (
+	"sync"
+
+	"github.com/IBM/sarama"
+)
+
+func newSyncProducer(h *Harness, transactional bool) *syncProducer {
+	return &syncProducer{
+		harness:       h,
+		transactional: transactional,
+		status:        sarama.ProducerTxnFlagReady,
+		offsetList:    make(map[string]int64),
+	}
+}
+
+// syncProducer produces messages to harness, transactional one publishes messages and consumed offsets on commit
+type syncProducer struct {
+	harness       *Harness
+	transactional bool
+
+	mu         sync.Mutex
+	closed     bool
+	status     sarama.ProducerTxnStatusFlag
+	txnList    []*sarama.ProducerMessage
+	offsetList map[string]int64
+}
+
+func (p *syncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return 0, 0, sarama.ErrShuttingDown
+	}
+	if !p.transactional {
+		return p.harness.produce(msg)
+	}
+	if p.status&sarama.ProducerTxnFlagInTransaction == 0 {
+		return 0, 0, sarama.ErrTransactionNotReady
+	}
+	if err := p.harness.produceError(); err != nil {
+		return 0, 0, err
+	}
+	p.txnList = append(p.txnList, msg)
+
+	return 0, 0, nil
+}
+
+func (p *syncProducer) SendMessages(msgList []*sarama.ProducerMessage) error {
+	for _, msg := range msgList {
+		if _, _, err := p.SendMessage(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *syncProducer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+
+	return nil
+}
+
+func (p *syncProducer) TxnStatus() sarama.ProducerTxnStatusFlag {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.status
+}
+
+func (p *syncProducer) IsTransactional() bool {
+	return p.transactional
+}
+
+func (p *syncProducer) BeginTxn() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.transactional {
+		return sarama.ErrNonTransactedProducer
+	}
+	if p.status != sarama.ProducerTxnFlagReady {
+		return sarama.ErrTransactionNotReady
+	}
+	p.status = sarama.ProducerTxnFlagInTransaction
+
+	return nil
+}
+
+func (p *syncProducer) CommitTxn() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.status&sarama.ProducerTxnFlagInTransaction == 0 {
+		return sarama.ErrTransactionNotReady
+	}
+	for _, msg := range p.txnList {
+		if _, _, err := p.harness.produce(msg); err != nil {
+			p.status |= sarama.ProducerTxnFlagInError | sarama.ProducerTxnFlagAbortableError
+
+			return err
+		}
+	}
+	for topic, offset := range p.offsetList {
+		p.harness.mark(topic, offset, false)
+	}
+	p.reset()
+
+	return nil
+}
+
+func (p *syncProducer) AbortTxn() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.status&sarama.ProducerTxnFlagInTransaction == 0 {
+		return sarama.ErrTransactionNotReady
+	}
+	p.reset()
+
+	return nil
+}
+
+func (p *syncProducer) AddOffsetsToTxn(offsetList map[string][]*sarama.PartitionOffsetMetadata, _ string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.status&sarama.ProducerTxnFlagInTransaction == 0 {
+		return sarama.ErrTransactionNotReady
+	}
+	for topic, partitionList := range offsetList {
+		for _, partition := range partitionList {
+			p.offsetList[topic] = partition.Offset
+		}
+	}
+
+	return nil
+}
+
+func (p *syncProducer) AddMessageToTxn(msg *sarama.ConsumerMessage, groupID string, metadata *string) error {
+	return p.AddOffsetsToTxn(map[string][]*sarama.PartitionOffsetMetadata{
+		msg.Topic: {{Partition: msg.Partition, Offset: msg.Offset + 1, Metadata: metadata}},
+	}, groupID)
+}
+
+// reset finishes transaction, must be called under lock
+func (p *syncProducer) reset() {
+	p.status = sarama.ProducerTxnFlagReady
+	p.txnList = nil
+	p.offsetList = make(map[string]int64)
+}
+
+func newAsyncProducer(h *Harness) *asyncProducer {
+	p := &asyncProducer{
+		inputChan:   make(chan *sarama.ProducerMessage),
+		successChan: make(chan *sarama.ProducerMessage, sarama.NewConfig().ChannelBufferSize),
+		errorChan:   make(chan *sarama.ProducerError, sarama.NewConfig().ChannelBufferSize),
+	}
+	go func() {
+		defer close(p.successChan)
+		defer close(p.errorChan)
+
+		for msg := range p.inputChan {
+			if _, _, err := h.produce(msg); err != nil {
+				p.errorChan <- &sarama.ProducerError{Msg: msg, Err: err}
+
+				continue
+			}
+			p.successChan <- msg
+		}
+	}()
+
+	return p
+}
+
+// asyncProducer produces messages to harness in order of input, it is not transactional
+type asyncProducer struct {
+	inputChan   chan *sarama.ProducerMessage
+	successChan chan *sarama.ProducerMessage
+	errorChan   chan *sarama.ProducerError
+	closeOnce   sync.Once
+}
+
+func (p *asyncProducer) AsyncClose() {
+	p.closeOnce.Do(func() {
+		close(p.inputChan)
+	})
+}
+
+// Close flushes input and returns produce errors which were not read
+func (p *asyncProducer) Close() error {
+	p.AsyncClose()
+
+	go func() {
+		for range p.successChan {
+		}
+	}()
+	var result sarama.ProducerErrors
+	for err := range p.errorChan {
+		result = append(result, err)
+	}
+	if len(result) > 0 {
+		return result
+	}
+
+	return nil
+}
+
+func (p *asyncProducer) Input() chan<- *sarama.ProducerMessage {
+	return p.inputChan
+}
+
+func (p *asyncProducer) Successes() <-chan *sarama.ProducerMessage {
+	return p.successChan
+}
+
+func (p *asyncProducer) Errors() <-chan *sarama.ProducerError {
+	return p.errorChan
+}
+
+func (p *asyncProducer) IsTransactional() bool {
+	return false
+}
+
+func (p *asyncProducer) TxnStatus() sarama.ProducerTxnStatusFlag {
+	return sarama.ProducerTxnFlagReady
+}
+
+func (p *asyncProducer) BeginTxn() error {
+	return sarama.ErrNonTransactedProducer
+}
+
+func (p *asyncProducer) CommitTxn() error {
+	return sarama.ErrNonTransactedProducer
+}
+
+func (p *asyncProducer) AbortTxn() error {
+	return sarama.ErrNonTransactedProducer
+}
+
+func (p *asyncProducer) AddOffsetsToTxn(map[string][]*sarama.PartitionOffsetMetadata, string) error {
+	return sarama.ErrNonTransactedProducer
+}
+
+func (p *asyncProducer) AddMessageToTxn(*sarama.ConsumerMessage, string, *string) error {
+	return sarama.ErrNonTransactedProducer
+}
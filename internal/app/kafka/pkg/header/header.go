@@ -0,0 +1,101 @@
+// Package header defines Kafka record headers used by services
+package header
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/IBM/sarama"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+const (
+	CorrelationID = "correlation_id"
+
+	MessageType   = "message_type"
+	ContentType   = "content_type"
+	SchemaVersion = "schema_version"
+	ProducerID    = "producer_id"
+	// TraceParent is W3C trace context header
+	TraceParent = "traceparent"
+
+	OriginalTopic     = "original_topic"
+	OriginalPartition = "original_partition"
+	OriginalOffset    = "original_offset"
+	Error             = "error"
+	Attempt           = "attempt"
+)
+
+// New returns headers describing produced message and propagating trace context of ctx
+func New(ctx context.Context, messageType, contentType, producerID string) []sarama.RecordHeader {
+	headerList := []sarama.RecordHeader{
+		{Key: []byte(MessageType), Value: []byte(messageType)},
+		{Key: []byte(ContentType), Value: []byte(contentType)},
+		{Key: []byte(SchemaVersion), Value: []byte(strconv.Itoa(protocol.SchemaVersion))},
+	}
+	if producerID != "" {
+		headerList = Set(headerList, ProducerID, producerID)
+	}
+	if sc := tracer.SpanContextFromContext(ctx); sc.IsValid() {
+		headerList = Set(headerList, TraceParent, sc.TraceParent())
+	}
+
+	return headerList
+}
+
+// Context returns ctx continuing trace started by producer of consumed message, if any
+func Context(ctx context.Context, headerList []*sarama.RecordHeader) context.Context {
+	sc, err := tracer.ParseTraceParent(Get(headerList, TraceParent))
+	if err != nil {
+		return ctx
+	}
+
+	return tracer.ContextWithSpanContext(ctx, sc)
+}
+
+// Get returns value of consumed record header by key
+func Get(headerList []*sarama.RecordHeader, key string) string {
+	for _, h := range headerList {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+// Copy converts consumed record headers to produced ones skipping excluded keys
+func Copy(headerList []*sarama.RecordHeader, excludedKeyList ...string) []sarama.RecordHeader {
+	excluded := make(map[string]struct{}, len(excludedKeyList))
+	for _, key := range excludedKeyList {
+		excluded[key] = struct{}{}
+	}
+
+	result := make([]sarama.RecordHeader, 0, len(headerList))
+	for _, h := range headerList {
+		if h == nil {
+			continue
+		}
+		if _, ok := excluded[string(h.Key)]; ok {
+			continue
+		}
+		result = append(result, sarama.RecordHeader{Key: h.Key, Value: h.Value})
+	}
+
+	return result
+}
+
+// Set replaces produced record header value or appends header
+func Set(headerList []sarama.RecordHeader, key, value string) []sarama.RecordHeader {
+	for i := range headerList {
+		if string(headerList[i].Key) == key {
+			headerList[i].Value = []byte(value)
+
+			return headerList
+		}
+	}
+
+	return append(headerList, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
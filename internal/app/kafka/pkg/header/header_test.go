@@ -0,0 +1,75 @@
+package header
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+func consumed(headerList []sarama.RecordHeader) []*sarama.RecordHeader {
+	result := make([]*sarama.RecordHeader, 0, len(headerList))
+	for i := range headerList {
+		result = append(result, &headerList[i])
+	}
+
+	return result
+}
+
+func TestNew(t *testing.T) {
+	testCaseList := []struct {
+		name            string
+		args            func() (context.Context, string)
+		wantProducerID  string
+		wantTraceParent bool
+	}{
+		{
+			name: "With trace and producer id",
+			args: func() (context.Context, string) {
+				ctx, _ := tracer.Start(context.Background(), "example")
+
+				return ctx, "example"
+			},
+			wantProducerID:  "example",
+			wantTraceParent: true,
+		},
+		{
+			name: "Without trace and producer id",
+			args: func() (context.Context, string) {
+				return context.Background(), ""
+			},
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, producerID := tc.args()
+
+			result := consumed(New(ctx, "request", "gob", producerID))
+
+			assert.Equal(t, "request", Get(result, MessageType))
+			assert.Equal(t, "gob", Get(result, ContentType))
+			assert.Equal(t, strconv.Itoa(protocol.SchemaVersion), Get(result, SchemaVersion))
+			assert.Equal(t, tc.wantProducerID, Get(result, ProducerID))
+			assert.Equal(t, tc.wantTraceParent, Get(result, TraceParent) != "")
+			if tc.wantTraceParent {
+				assert.Equal(t, tracer.SpanContextFromContext(ctx), tracer.SpanContextFromContext(Context(context.Background(), result)))
+			}
+		})
+	}
+}
+
+func TestCopy(t *testing.T) {
+	headerList := consumed(Set(Set(nil, CorrelationID, "example"), Attempt, "1"))
+
+	result := consumed(Set(Copy(append(headerList, nil), Attempt), CorrelationID, "changed"))
+
+	assert.Len(t, result, 1)
+	assert.Equal(t, "changed", Get(result, CorrelationID))
+	assert.Equal(t, "example", Get(headerList, CorrelationID))
+}
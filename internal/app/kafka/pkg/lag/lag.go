@@ -0,0 +1,97 @@
+// Package lag measures how far consumer group is behind the end of Kafka topic partitions
+package lag
+
+import (
+	"context"
+	"sort"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+// ErrThreshold is reported for partitions which lag is above warning threshold
+var ErrThreshold = errors.New("lag above threshold")
+
+// Partition is lag of consumer group on topic partition
+type Partition struct {
+	Group         string
+	Topic         string
+	Partition     int32
+	HighWaterMark int64
+	// Offset is the next offset group consumes, -1 when group has no committed offset
+	Offset int64
+	Lag    int64
+}
+
+// Processed moves partition offset to offset processed but not committed yet
+func (p *Partition) Processed(offset int64) {
+	if offset <= p.Offset {
+		return
+	}
+	p.Offset = offset
+	p.Lag = max(p.HighWaterMark-offset, 0)
+}
+
+// Measure returns lag of group on every partition of topic list sorted by topic and partition,
+// partitions without committed offset lag by all their retained messages
+func Measure(
+	ctx context.Context,
+	client sarama.Client,
+	admin sarama.ClusterAdmin,
+	groupID string,
+	topicList []string,
+) ([]Partition, error) {
+	_, span := tracer.Start(ctx, "internal.app.kafka.pkg.lag.Measure")
+	defer span.End()
+
+	partitionList := make(map[string][]int32, len(topicList))
+	for _, topic := range topicList {
+		list, err := client.Partitions(topic)
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing partitions (%s)", topic)
+		}
+		partitionList[topic] = list
+	}
+
+	committed, err := admin.ListConsumerGroupOffsets(groupID, partitionList)
+	if err != nil {
+		return nil, errors.Wrap(err, "fetching committed offsets")
+	}
+	if !errors.Is(committed.Err, sarama.ErrNoError) {
+		return nil, errors.Wrap(committed.Err, "fetching committed offsets")
+	}
+
+	var result []Partition
+	for topic, list := range partitionList {
+		for _, partition := range list {
+			p := Partition{Group: groupID, Topic: topic, Partition: partition, Offset: -1}
+			if p.HighWaterMark, err = client.GetOffset(topic, partition, sarama.OffsetNewest); err != nil {
+				return nil, errors.Wrapf(err, "fetching high water mark (%s/%d)", topic, partition)
+			}
+
+			start := int64(-1)
+			if block := committed.GetBlock(topic, partition); block != nil && block.Offset >= 0 {
+				p.Offset, start = block.Offset, block.Offset
+			}
+			if start < 0 {
+				if start, err = client.GetOffset(topic, partition, sarama.OffsetOldest); err != nil {
+					return nil, errors.Wrapf(err, "fetching oldest offset (%s/%d)", topic, partition)
+				}
+			}
+			p.Lag = max(p.HighWaterMark-start, 0)
+
+			result = append(result, p)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Topic != result[j].Topic {
+			return result[i].Topic < result[j].Topic
+		}
+
+		return result[i].Partition < result[j].Partition
+	})
+
+	return result, nil
+}
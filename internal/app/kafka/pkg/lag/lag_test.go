@@ -0,0 +1,84 @@
+package lag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasure(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       sarama.MockResponse
+		wantResult []Partition
+		wantError  bool
+	}{
+		{
+			name: "Committed and not committed partitions",
+			args: sarama.NewMockOffsetFetchResponse(t).
+				SetOffset("example", "a", 0, 4, "", sarama.ErrNoError).
+				SetOffset("example", "b", 0, -1, "", sarama.ErrNoError),
+			wantResult: []Partition{
+				{Group: "example", Topic: "a", Partition: 0, HighWaterMark: 10, Offset: 4, Lag: 6},
+				{Group: "example", Topic: "b", Partition: 0, HighWaterMark: 10, Offset: -1, Lag: 8},
+			},
+		},
+		{
+			name:      "Coordinator error",
+			args:      sarama.NewMockOffsetFetchResponse(t).SetError(sarama.ErrGroupAuthorizationFailed),
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			broker := sarama.NewMockBroker(t, 1)
+			defer broker.Close()
+			broker.SetHandlerByMap(map[string]sarama.MockResponse{
+				"MetadataRequest": sarama.NewMockMetadataResponse(t).
+					SetBroker(broker.Addr(), broker.BrokerID()).
+					SetController(broker.BrokerID()).
+					SetLeader("a", 0, broker.BrokerID()).
+					SetLeader("b", 0, broker.BrokerID()),
+				"OffsetRequest": sarama.NewMockOffsetResponse(t).
+					SetOffset("a", 0, sarama.OffsetNewest, 10).
+					SetOffset("b", 0, sarama.OffsetNewest, 10).
+					SetOffset("b", 0, sarama.OffsetOldest, 2),
+				"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+					SetCoordinator(sarama.CoordinatorGroup, "example", broker),
+				"OffsetFetchRequest": tc.args,
+			})
+			client, err := sarama.NewClient([]string{broker.Addr()}, sarama.NewConfig())
+			require.NoError(t, err)
+			defer func() {
+				_ = client.Close()
+			}()
+			admin, err := sarama.NewClusterAdminFromClient(client)
+			require.NoError(t, err)
+
+			result, err := Measure(context.Background(), client, admin, "example", []string{"b", "a"})
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, result)
+		})
+	}
+}
+
+func TestPartition_Processed(t *testing.T) {
+	p := Partition{HighWaterMark: 10, Offset: 4, Lag: 6}
+
+	p.Processed(3)
+	assert.Equal(t, int64(6), p.Lag)
+
+	p.Processed(8)
+	assert.Equal(t, int64(8), p.Offset)
+	assert.Equal(t, int64(2), p.Lag)
+}
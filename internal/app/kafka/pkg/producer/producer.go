@@ -0,0 +1,176 @@
+// Package producer implements Kafka producer sending messages in batches
+package producer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
+	"github.com/kirill-a-belov/test_task_framework/pkg/metrics"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+var ErrClosed = errors.New("producer closed")
+
+// Producer sends message and waits for its delivery result, sarama.SyncProducer satisfies it
+type Producer interface {
+	SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error)
+	Close() error
+}
+
+// Sender enqueues message without waiting for its delivery result
+type Sender interface {
+	Send(msg *sarama.ProducerMessage) error
+}
+
+// Send enqueues message without waiting when producer is a Sender, e.g. best effort replies,
+// otherwise sends it and waits for delivery result, messages whose loss must block acknowledgement use SendMessage
+func Send(p Producer, msg *sarama.ProducerMessage) error {
+	if s, ok := p.(Sender); ok {
+		return s.Send(msg)
+	}
+	_, _, err := p.SendMessage(msg)
+
+	return err
+}
+
+func New(ctx context.Context, producer sarama.AsyncProducer) *Async {
+	_, span := tracer.Start(ctx, "internal.app.kafka.pkg.producer.New")
+	defer span.End()
+
+	a := &Async{
+		producer: producer,
+		logger:   logger.New("kafka.producer"),
+
+		sentCounter:   metrics.NewCounter("kafka.producer.sent"),
+		failedCounter: metrics.NewCounter("kafka.producer.failed"),
+		inFlightGauge: metrics.NewGauge("kafka.producer.in_flight"),
+	}
+	a.wg.Add(2)
+	go a.drainSuccesses()
+	go a.drainErrors()
+
+	return a
+}
+
+// Async sends messages through async producer, so messages of concurrent senders are batched together,
+// delivery results are drained to metrics and passed to waiting senders, failures of not awaited messages
+// are returned by Close
+type Async struct {
+	producer sarama.AsyncProducer
+	logger   logger.Logger
+	wg       sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+
+	lostMu  sync.Mutex
+	lostErr error
+	lostCnt int
+
+	sentCounter   *metrics.Counter
+	failedCounter *metrics.Counter
+	inFlightGauge *metrics.Gauge
+}
+
+// SendMessage enqueues message and waits for its delivery result, message metadata is overwritten
+func (a *Async) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	resultChan := make(chan error, 1)
+	msg.Metadata = resultChan
+
+	if err := a.enqueue(msg); err != nil {
+		return 0, 0, err
+	}
+	if err := <-resultChan; err != nil {
+		return 0, 0, err
+	}
+
+	return msg.Partition, msg.Offset, nil
+}
+
+// Send enqueues message without waiting for its delivery result, message metadata is overwritten
+func (a *Async) Send(msg *sarama.ProducerMessage) error {
+	msg.Metadata = nil
+
+	return a.enqueue(msg)
+}
+
+func (a *Async) enqueue(msg *sarama.ProducerMessage) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		return ErrClosed
+	}
+	a.inFlightGauge.Add(1)
+	a.producer.Input() <- msg
+
+	return nil
+}
+
+// Close flushes buffered messages and waits for their delivery results,
+// returns first delivery error of messages sent without waiting
+func (a *Async) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+
+		return nil
+	}
+	a.closed = true
+	a.mu.Unlock()
+
+	a.producer.AsyncClose()
+	a.wg.Wait()
+
+	a.lostMu.Lock()
+	defer a.lostMu.Unlock()
+
+	if a.lostErr != nil {
+		return errors.Wrapf(a.lostErr, "%d messages not delivered", a.lostCnt)
+	}
+
+	return nil
+}
+
+func (a *Async) drainSuccesses() {
+	defer a.wg.Done()
+
+	for msg := range a.producer.Successes() {
+		a.sentCounter.Inc()
+		a.complete(msg, nil)
+	}
+}
+
+func (a *Async) drainErrors() {
+	defer a.wg.Done()
+
+	for producerErr := range a.producer.Errors() {
+		a.failedCounter.Inc()
+		a.logger.Error(producerErr.Err, "message delivery", "topic", producerErr.Msg.Topic)
+		a.complete(producerErr.Msg, producerErr.Err)
+	}
+}
+
+func (a *Async) complete(msg *sarama.ProducerMessage, err error) {
+	a.inFlightGauge.Add(-1)
+	if resultChan, ok := msg.Metadata.(chan error); ok {
+		resultChan <- err
+
+		return
+	}
+	if err == nil {
+		return
+	}
+
+	a.lostMu.Lock()
+	defer a.lostMu.Unlock()
+
+	if a.lostErr == nil {
+		a.lostErr = err
+	}
+	a.lostCnt++
+}
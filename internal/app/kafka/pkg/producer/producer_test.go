@@ -0,0 +1,156 @@
+package producer
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exampleTopic = "example_topic"
+
+func newMockProducer(t *testing.T) *mocks.AsyncProducer {
+	cfg := mocks.NewTestConfig()
+	cfg.Producer.Return.Successes = true
+
+	return mocks.NewAsyncProducer(t, cfg)
+}
+
+func TestAsync_SendMessage(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      func(p *mocks.AsyncProducer)
+		wantError error
+	}{
+		{
+			name: "Success",
+			args: func(p *mocks.AsyncProducer) {
+				p.ExpectInputAndSucceed()
+			},
+		},
+		{
+			name: "Delivery error",
+			args: func(p *mocks.AsyncProducer) {
+				p.ExpectInputAndFail(assert.AnError)
+			},
+			wantError: assert.AnError,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			mockProducer := newMockProducer(t)
+			tc.args(mockProducer)
+			a := New(context.Background(), mockProducer)
+			sent, failed := a.sentCounter.Value(), a.failedCounter.Value()
+
+			_, _, err := a.SendMessage(&sarama.ProducerMessage{Topic: exampleTopic})
+			require.NoError(t, a.Close())
+
+			if tc.wantError != nil {
+				assert.ErrorIs(t, err, tc.wantError)
+				assert.Equal(t, failed+1, a.failedCounter.Value())
+
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, sent+1, a.sentCounter.Value())
+		})
+	}
+}
+
+func TestAsync_Close(t *testing.T) {
+	const messageCnt = 10
+
+	mockProducer := newMockProducer(t)
+	for i := 0; i < messageCnt; i++ {
+		mockProducer.ExpectInputAndSucceed()
+	}
+	a := New(context.Background(), mockProducer)
+	sent := a.sentCounter.Value()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < messageCnt; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := a.SendMessage(&sarama.ProducerMessage{Topic: exampleTopic})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.NoError(t, a.Close())
+	assert.Equal(t, sent+messageCnt, a.sentCounter.Value())
+	assert.Zero(t, a.inFlightGauge.Value())
+
+	_, _, err := a.SendMessage(&sarama.ProducerMessage{Topic: exampleTopic})
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.NoError(t, a.Close())
+}
+
+func TestAsync_Send(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      func(p *mocks.AsyncProducer)
+		wantError error
+	}{
+		{
+			name: "Success",
+			args: func(p *mocks.AsyncProducer) {
+				p.ExpectInputAndSucceed()
+			},
+		},
+		{
+			name: "Delivery error returned by close",
+			args: func(p *mocks.AsyncProducer) {
+				p.ExpectInputAndFail(assert.AnError)
+				p.ExpectInputAndSucceed()
+			},
+			wantError: assert.AnError,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			mockProducer := newMockProducer(t)
+			tc.args(mockProducer)
+			a := New(context.Background(), mockProducer)
+			sent, failed := a.sentCounter.Value(), a.failedCounter.Value()
+
+			require.NoError(t, Send(a, &sarama.ProducerMessage{Topic: exampleTopic}))
+			if tc.wantError != nil {
+				require.NoError(t, a.Send(&sarama.ProducerMessage{Topic: exampleTopic}))
+			}
+			err := a.Close()
+			assert.Zero(t, a.inFlightGauge.Value())
+
+			if tc.wantError != nil {
+				assert.ErrorIs(t, err, tc.wantError)
+				assert.Equal(t, failed+1, a.failedCounter.Value())
+				assert.Equal(t, sent+1, a.sentCounter.Value())
+
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, sent+1, a.sentCounter.Value())
+			assert.ErrorIs(t, a.Send(&sarama.ProducerMessage{Topic: exampleTopic}), ErrClosed)
+		})
+	}
+}
+
+func TestSend(t *testing.T) {
+	t.Run("Sync producer", func(t *testing.T) {
+		cfg := mocks.NewTestConfig()
+		cfg.Producer.Return.Successes = true
+		mockProducer := mocks.NewSyncProducer(t, cfg)
+		mockProducer.ExpectSendMessageAndFail(assert.AnError)
+
+		assert.ErrorIs(t, Send(mockProducer, &sarama.ProducerMessage{Topic: exampleTopic}), assert.AnError)
+		assert.NoError(t, mockProducer.Close())
+	})
+}
@@ -0,0 +1,165 @@
+// Package provision creates missing Kafka topics and reports drift of existing ones from their specs
+package provision
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/retry"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+// ErrDrift is reported for topic settings which differ from spec
+var ErrDrift = errors.New("topic drift")
+
+const (
+	configRetention     = "retention.ms"
+	configCleanupPolicy = "cleanup.policy"
+
+	settingPartitions        = "partitions"
+	settingReplicationFactor = "replication factor"
+)
+
+// Drift is setting of existing topic which differs from its spec, it is reported but not altered
+type Drift struct {
+	Topic   string
+	Setting string
+	Want    string
+	Got     string
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s: %s is %s, want %s", d.Topic, d.Setting, d.Got, d.Want)
+}
+
+// Report lists topics created, or validated only in dry run, and drift of existing topics
+type Report struct {
+	CreatedList []string
+	DriftList   []Drift
+}
+
+// Specs returns topic specs of config along with retry and dead letter topics of request topic,
+// they inherit settings of request topic
+func Specs(cfg *config.Config) ([]config.TopicSpec, error) {
+	specList, err := cfg.TopicSpecs()
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing topic list")
+	}
+
+	result := append([]config.TopicSpec(nil), specList...)
+	for _, spec := range specList {
+		if spec.Name != cfg.RequestTopic {
+			continue
+		}
+		for i := range cfg.RetryTopicDelayList {
+			retrySpec := spec
+			retrySpec.Name = retry.Topic(spec.Name, i+1)
+			result = append(result, retrySpec)
+		}
+		if cfg.DeadLetter {
+			deadLetterSpec := spec
+			deadLetterSpec.Name = retry.DeadLetterTopic(spec.Name)
+			result = append(result, deadLetterSpec)
+		}
+	}
+
+	return result, nil
+}
+
+// Reconcile creates topics of spec list missing in cluster and compares existing ones with their specs,
+// in dry run topic creation is only validated by broker
+func Reconcile(ctx context.Context, admin sarama.ClusterAdmin, specList []config.TopicSpec, dryRun bool) (*Report, error) {
+	_, span := tracer.Start(ctx, "internal.app.kafka.pkg.provision.Reconcile")
+	defer span.End()
+
+	existingList, err := admin.ListTopics()
+	if err != nil {
+		return nil, errors.Wrap(err, "listing topics")
+	}
+
+	result := &Report{}
+	for _, spec := range specList {
+		detail, ok := existingList[spec.Name]
+		if !ok {
+			if err := admin.CreateTopic(spec.Name, topicDetail(spec), dryRun); err != nil {
+				return result, errors.Wrapf(err, "creating topic (%s)", spec.Name)
+			}
+			result.CreatedList = append(result.CreatedList, spec.Name)
+
+			continue
+		}
+
+		result.DriftList = append(result.DriftList, drift(spec, detail)...)
+	}
+
+	return result, nil
+}
+
+func topicDetail(spec config.TopicSpec) *sarama.TopicDetail {
+	result := &sarama.TopicDetail{
+		NumPartitions:     spec.Partitions,
+		ReplicationFactor: spec.ReplicationFactor,
+		ConfigEntries:     make(map[string]*string),
+	}
+	for name, value := range configList(spec) {
+		value := value
+		result.ConfigEntries[name] = &value
+	}
+
+	return result
+}
+
+// configList returns topic configs managed by spec, others are kept at broker defaults
+func configList(spec config.TopicSpec) map[string]string {
+	result := make(map[string]string)
+	if spec.Retention > 0 {
+		result[configRetention] = strconv.FormatInt(spec.Retention.Milliseconds(), 10)
+	}
+	if spec.CleanupPolicy != "" {
+		result[configCleanupPolicy] = spec.CleanupPolicy
+	}
+
+	return result
+}
+
+func drift(spec config.TopicSpec, detail sarama.TopicDetail) []Drift {
+	var result []Drift
+	if detail.NumPartitions != spec.Partitions {
+		result = append(result, Drift{
+			Topic:   spec.Name,
+			Setting: settingPartitions,
+			Want:    strconv.Itoa(int(spec.Partitions)),
+			Got:     strconv.Itoa(int(detail.NumPartitions)),
+		})
+	}
+	if detail.ReplicationFactor != spec.ReplicationFactor {
+		result = append(result, Drift{
+			Topic:   spec.Name,
+			Setting: settingReplicationFactor,
+			Want:    strconv.Itoa(int(spec.ReplicationFactor)),
+			Got:     strconv.Itoa(int(detail.ReplicationFactor)),
+		})
+	}
+	managedList := configList(spec)
+	for _, name := range []string{configRetention, configCleanupPolicy} {
+		want, ok := managedList[name]
+		if !ok {
+			continue
+		}
+		// broker omits configs kept at its default, their values are unknown, so they are not reported
+		got := detail.ConfigEntries[name]
+		if got == nil {
+			continue
+		}
+		if *got != want {
+			result = append(result, Drift{Topic: spec.Name, Setting: name, Want: want, Got: *got})
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,148 @@
+package provision
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+)
+
+func TestSpecs(t *testing.T) {
+	result, err := Specs(&config.Config{
+		TopicList:           []string{"a:request::3", "b:response"},
+		RequestTopic:        "a",
+		RetryTopicDelayList: []time.Duration{time.Second},
+		DeadLetter:          true,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, result, 4)
+	assert.Equal(t, "a.retry.1", result[2].Name)
+	assert.Equal(t, "a.dlq", result[3].Name)
+	assert.Equal(t, int32(3), result[3].Partitions)
+}
+
+func TestReconcile(t *testing.T) {
+	specList := []config.TopicSpec{
+		{Name: "a", Partitions: 3, ReplicationFactor: 1, Retention: 24 * time.Hour},
+		{Name: "b", Partitions: 1, ReplicationFactor: 1, CleanupPolicy: config.CleanupPolicyCompact},
+	}
+
+	testCaseList := []struct {
+		name      string
+		args      bool
+		wantDrift []Drift
+	}{
+		{
+			name: "Missing topic is created",
+			args: false,
+			wantDrift: []Drift{
+				{Topic: "a", Setting: settingPartitions, Want: "3", Got: "1"},
+				{Topic: "a", Setting: configRetention, Want: "86400000", Got: "5000"},
+			},
+		},
+		{
+			name: "Dry run",
+			args: true,
+			wantDrift: []Drift{
+				{Topic: "a", Setting: settingPartitions, Want: "3", Got: "1"},
+				{Topic: "a", Setting: configRetention, Want: "86400000", Got: "5000"},
+			},
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			broker := sarama.NewMockBroker(t, 1)
+			defer broker.Close()
+			broker.SetHandlerByMap(map[string]sarama.MockResponse{
+				"MetadataRequest": sarama.NewMockMetadataResponse(t).
+					SetBroker(broker.Addr(), broker.BrokerID()).
+					SetController(broker.BrokerID()).
+					SetLeader("a", 0, broker.BrokerID()),
+				"DescribeConfigsRequest": sarama.NewMockDescribeConfigsResponse(t),
+				"CreateTopicsRequest":    sarama.NewMockCreateTopicsResponse(t),
+			})
+			admin, err := sarama.NewClusterAdmin([]string{broker.Addr()}, sarama.NewConfig())
+			require.NoError(t, err)
+			defer func() {
+				_ = admin.Close()
+			}()
+
+			result, err := Reconcile(context.Background(), admin, specList, tc.args)
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"b"}, result.CreatedList)
+			assert.Equal(t, tc.wantDrift, result.DriftList)
+
+			var request *sarama.CreateTopicsRequest
+			for _, entry := range broker.History() {
+				if r, ok := entry.Request.(*sarama.CreateTopicsRequest); ok {
+					request = r
+				}
+			}
+			require.NotNil(t, request)
+			assert.Equal(t, tc.args, request.ValidateOnly)
+			require.Contains(t, request.TopicDetails, "b")
+			assert.Equal(t, config.CleanupPolicyCompact, *request.TopicDetails["b"].ConfigEntries[configCleanupPolicy])
+		})
+	}
+}
+
+func Test_drift(t *testing.T) {
+	spec := config.TopicSpec{
+		Name:              "a",
+		Partitions:        1,
+		ReplicationFactor: 1,
+		Retention:         time.Hour,
+		CleanupPolicy:     config.CleanupPolicyCompact,
+	}
+	value := func(v string) *string {
+		return &v
+	}
+
+	testCaseList := []struct {
+		name      string
+		args      map[string]*string
+		wantDrift []Drift
+	}{
+		{
+			name: "Configs match",
+			args: map[string]*string{
+				configRetention:     value("3600000"),
+				configCleanupPolicy: value(config.CleanupPolicyCompact),
+			},
+		},
+		{
+			name: "Config differs",
+			args: map[string]*string{
+				configRetention:     value("5000"),
+				configCleanupPolicy: value(config.CleanupPolicyCompact),
+			},
+			wantDrift: []Drift{
+				{Topic: "a", Setting: configRetention, Want: "3600000", Got: "5000"},
+			},
+		},
+		{
+			name: "Configs kept at broker default",
+			args: map[string]*string{},
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			result := drift(spec, sarama.TopicDetail{
+				NumPartitions:     1,
+				ReplicationFactor: 1,
+				ConfigEntries:     tc.args,
+			})
+
+			assert.Equal(t, tc.wantDrift, result)
+		})
+	}
+}
@@ -0,0 +1,283 @@
+// Package retry implements delayed retry topics and dead letter topic for failed Kafka messages
+package retry
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/header"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/producer"
+	"github.com/kirill-a-belov/test_task_framework/pkg/backoff"
+	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
+	"github.com/kirill-a-belov/test_task_framework/pkg/metrics"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+var ErrNoOriginalTopic = errors.New("no original topic")
+
+// pipelineHeaderList are headers describing message position in pipeline
+var pipelineHeaderList = []string{
+	header.OriginalTopic,
+	header.OriginalPartition,
+	header.OriginalOffset,
+	header.Error,
+	header.Attempt,
+}
+
+// Topic returns name of retry topic for attempt starting from 1
+func Topic(topic string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", topic, attempt)
+}
+
+// DeadLetterTopic returns name of dead letter topic
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+// OriginalTopic returns topic message was initially consumed from
+func OriginalTopic(msg *sarama.ConsumerMessage) string {
+	if topic := header.Get(msg.Headers, header.OriginalTopic); topic != "" {
+		return topic
+	}
+
+	return msg.Topic
+}
+
+// Attempt returns number of retry attempt message was produced for, 0 for original messages
+func Attempt(msg *sarama.ConsumerMessage) int {
+	attempt, _ := strconv.Atoi(header.Get(msg.Headers, header.Attempt))
+
+	return attempt
+}
+
+func New(ctx context.Context, config *config.Config, producer producer.Producer) *Pipeline {
+	_, span := tracer.Start(ctx, "internal.app.kafka.pkg.retry.New")
+	defer span.End()
+
+	return &Pipeline{
+		delayList:  config.RetryTopicDelayList,
+		deadLetter: config.DeadLetter,
+		producer:   producer,
+		logger:     logger.New("kafka.retry"),
+
+		retriedCounter:    metrics.NewCounter("kafka.retry.routed"),
+		deadLetterCounter: metrics.NewCounter("kafka.dead_letter.routed"),
+		droppedCounter:    metrics.NewCounter("kafka.retry.dropped"),
+	}
+}
+
+// Pipeline routes failed messages through delayed retry topics and then to dead letter topic
+type Pipeline struct {
+	delayList  []time.Duration
+	deadLetter bool
+	producer   producer.Producer
+	logger     logger.Logger
+
+	retriedCounter    *metrics.Counter
+	deadLetterCounter *metrics.Counter
+	droppedCounter    *metrics.Counter
+}
+
+// Topics returns retry topics of topic which have to be consumed along with it
+func (p *Pipeline) Topics(topic string) []string {
+	result := make([]string, 0, len(p.delayList))
+	for i := range p.delayList {
+		result = append(result, Topic(topic, i+1))
+	}
+
+	return result
+}
+
+// Wait delays retry message processing until its retry delay has passed since it was produced
+func (p *Pipeline) Wait(ctx context.Context, msg *sarama.ConsumerMessage) error {
+	attempt := Attempt(msg)
+	if attempt < 1 || attempt > len(p.delayList) {
+		return nil
+	}
+
+	return backoff.Wait(ctx, time.Until(msg.Timestamp.Add(p.delayList[attempt-1])))
+}
+
+// Fail routes failed message to the next retry topic or to dead letter topic once retries are exhausted
+func (p *Pipeline) Fail(msg *sarama.ConsumerMessage, cause error) error {
+	originalTopic := OriginalTopic(msg)
+	attempt := Attempt(msg) + 1
+
+	var destination string
+	switch {
+	case attempt <= len(p.delayList):
+		destination = Topic(originalTopic, attempt)
+	case p.deadLetter:
+		destination = DeadLetterTopic(originalTopic)
+	default:
+		p.droppedCounter.Inc()
+
+		return nil
+	}
+
+	headerList := header.Copy(msg.Headers)
+	if header.Get(msg.Headers, header.OriginalTopic) == "" {
+		headerList = header.Set(headerList, header.OriginalTopic, msg.Topic)
+		headerList = header.Set(headerList, header.OriginalPartition, strconv.Itoa(int(msg.Partition)))
+		headerList = header.Set(headerList, header.OriginalOffset, strconv.FormatInt(msg.Offset, 10))
+	}
+	headerList = header.Set(headerList, header.Error, cause.Error())
+	headerList = header.Set(headerList, header.Attempt, strconv.Itoa(attempt))
+
+	// delivery is awaited, failed message must stay unacknowledged until it is routed
+	if _, _, err := p.producer.SendMessage(&sarama.ProducerMessage{
+		Topic:   destination,
+		Key:     sarama.ByteEncoder(msg.Key),
+		Value:   sarama.ByteEncoder(msg.Value),
+		Headers: headerList,
+	}); err != nil {
+		return errors.Wrapf(err, "sending failed message to (%s)", destination)
+	}
+
+	if destination == DeadLetterTopic(originalTopic) {
+		p.deadLetterCounter.Inc()
+	} else {
+		p.retriedCounter.Inc()
+	}
+	p.logger.Info("failed message routed",
+		"topic", msg.Topic,
+		"partition", msg.Partition,
+		"offset", msg.Offset,
+		"destination", destination,
+	)
+
+	return nil
+}
+
+// idleTimeout ends partition redrive when no message arrives before high water mark,
+// dead letter topics written by transactional producer end with commit markers which are never delivered
+var idleTimeout = 5 * time.Second
+
+// Redrive moves dead letter messages produced before the call back to their original topics,
+// progress is committed for group so every message is re-driven once, limit 0 means no limit
+func Redrive(
+	ctx context.Context,
+	client sarama.Client,
+	producer producer.Producer,
+	topic string,
+	groupID string,
+	limit int,
+) (int, error) {
+	ctx, span := tracer.Start(ctx, "internal.app.kafka.pkg.retry.Redrive")
+	defer span.End()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return 0, errors.Wrap(err, "creating consumer")
+	}
+	defer func() {
+		_ = consumer.Close()
+	}()
+
+	offsetManager, err := sarama.NewOffsetManagerFromClient(groupID, client)
+	if err != nil {
+		return 0, errors.Wrap(err, "creating offset manager")
+	}
+	defer func() {
+		_ = offsetManager.Close()
+	}()
+
+	partitionList, err := client.Partitions(topic)
+	if err != nil {
+		return 0, errors.Wrap(err, "listing partitions")
+	}
+
+	redrivenCnt := 0
+	for _, partition := range partitionList {
+		if limit > 0 && redrivenCnt >= limit {
+			break
+		}
+
+		cnt, err := redrivePartition(ctx, client, consumer, offsetManager, producer, topic, partition, limit-redrivenCnt)
+		redrivenCnt += cnt
+		if err != nil {
+			return redrivenCnt, errors.Wrapf(err, "redriving partition (%d)", partition)
+		}
+	}
+
+	return redrivenCnt, nil
+}
+
+func redrivePartition(
+	ctx context.Context,
+	client sarama.Client,
+	consumer sarama.Consumer,
+	offsetManager sarama.OffsetManager,
+	producer producer.Producer,
+	topic string,
+	partition int32,
+	limit int,
+) (int, error) {
+	highWaterMark, err := client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, errors.Wrap(err, "fetching high water mark")
+	}
+
+	pom, err := offsetManager.ManagePartition(topic, partition)
+	if err != nil {
+		return 0, errors.Wrap(err, "managing partition offset")
+	}
+	defer func() {
+		_ = pom.Close()
+	}()
+
+	offset, _ := pom.NextOffset()
+	if offset < 0 {
+		if offset, err = client.GetOffset(topic, partition, sarama.OffsetOldest); err != nil {
+			return 0, errors.Wrap(err, "fetching oldest offset")
+		}
+	}
+	if offset >= highWaterMark {
+		return 0, nil
+	}
+
+	pc, err := consumer.ConsumePartition(topic, partition, offset)
+	if err != nil {
+		return 0, errors.Wrap(err, "consuming partition")
+	}
+	defer func() {
+		_ = pc.Close()
+	}()
+
+	redrivenCnt := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return redrivenCnt, ctx.Err()
+		case err := <-pc.Errors():
+			return redrivenCnt, err
+		case <-time.After(idleTimeout):
+			return redrivenCnt, nil
+		case msg := <-pc.Messages():
+			originalTopic := header.Get(msg.Headers, header.OriginalTopic)
+			if originalTopic == "" {
+				return redrivenCnt, errors.Wrapf(ErrNoOriginalTopic, "offset (%d)", msg.Offset)
+			}
+			if _, _, err := producer.SendMessage(&sarama.ProducerMessage{
+				Topic:   originalTopic,
+				Key:     sarama.ByteEncoder(msg.Key),
+				Value:   sarama.ByteEncoder(msg.Value),
+				Headers: header.Copy(msg.Headers, pipelineHeaderList...),
+			}); err != nil {
+				return redrivenCnt, errors.Wrapf(err, "sending message to (%s)", originalTopic)
+			}
+			pom.MarkOffset(msg.Offset+1, "")
+			redrivenCnt++
+
+			if msg.Offset+1 >= highWaterMark || (limit > 0 && redrivenCnt >= limit) {
+				return redrivenCnt, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/header"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/producer"
+)
+
+const exampleTopic = "example_topic"
+
+type producerStub struct {
+	sarama.SyncProducer
+	sentList []*sarama.ProducerMessage
+	err      error
+}
+
+func (p *producerStub) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	if p.err != nil {
+		return 0, 0, p.err
+	}
+	p.sentList = append(p.sentList, msg)
+
+	return 0, 0, nil
+}
+
+func producedHeader(msg *sarama.ProducerMessage, key string) string {
+	for _, h := range msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+func TestPipeline_Topics(t *testing.T) {
+	p := New(context.Background(), &config.Config{
+		RetryTopicDelayList: []time.Duration{time.Second, time.Minute},
+	}, nil)
+
+	assert.Equal(t, []string{"example_topic.retry.1", "example_topic.retry.2"}, p.Topics(exampleTopic))
+	assert.Equal(t, "example_topic.dlq", DeadLetterTopic(exampleTopic))
+}
+
+func TestPipeline_Fail(t *testing.T) {
+	testCaseList := []struct {
+		name            string
+		args            func() (*config.Config, *sarama.ConsumerMessage)
+		wantTopic       string
+		wantAttempt     string
+		wantOriginalOff string
+	}{
+		{
+			name: "First failure goes to first retry topic",
+			args: func() (*config.Config, *sarama.ConsumerMessage) {
+				return &config.Config{RetryTopicDelayList: []time.Duration{time.Second}, DeadLetter: true},
+					&sarama.ConsumerMessage{Topic: exampleTopic, Partition: 1, Offset: 42}
+			},
+			wantTopic:       "example_topic.retry.1",
+			wantAttempt:     "1",
+			wantOriginalOff: "42",
+		},
+		{
+			name: "Exhausted retries go to dead letter topic",
+			args: func() (*config.Config, *sarama.ConsumerMessage) {
+				return &config.Config{RetryTopicDelayList: []time.Duration{time.Second}, DeadLetter: true},
+					&sarama.ConsumerMessage{
+						Topic:  "example_topic.retry.1",
+						Offset: 7,
+						Headers: []*sarama.RecordHeader{
+							{Key: []byte(header.OriginalTopic), Value: []byte(exampleTopic)},
+							{Key: []byte(header.OriginalOffset), Value: []byte("42")},
+							{Key: []byte(header.Attempt), Value: []byte("1")},
+						},
+					}
+			},
+			wantTopic:       "example_topic.dlq",
+			wantAttempt:     "2",
+			wantOriginalOff: "42",
+		},
+		{
+			name: "Dropped without dead letter topic",
+			args: func() (*config.Config, *sarama.ConsumerMessage) {
+				return &config.Config{}, &sarama.ConsumerMessage{Topic: exampleTopic}
+			},
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, msg := tc.args()
+			producer := &producerStub{}
+			require.NoError(t, New(context.Background(), cfg, producer).Fail(msg, assert.AnError))
+			if tc.wantTopic == "" {
+				assert.Empty(t, producer.sentList)
+
+				return
+			}
+			require.Len(t, producer.sentList, 1)
+			sent := producer.sentList[0]
+			assert.Equal(t, tc.wantTopic, sent.Topic)
+			assert.Equal(t, exampleTopic, producedHeader(sent, header.OriginalTopic))
+			assert.Equal(t, tc.wantOriginalOff, producedHeader(sent, header.OriginalOffset))
+			assert.Equal(t, tc.wantAttempt, producedHeader(sent, header.Attempt))
+			assert.Equal(t, assert.AnError.Error(), producedHeader(sent, header.Error))
+		})
+	}
+
+	t.Run("Producer error", func(t *testing.T) {
+		producer := &producerStub{err: assert.AnError}
+		err := New(context.Background(), &config.Config{DeadLetter: true}, producer).
+			Fail(&sarama.ConsumerMessage{Topic: exampleTopic}, assert.AnError)
+
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("Async producer delivery error", func(t *testing.T) {
+		cfg := mocks.NewTestConfig()
+		cfg.Producer.Return.Successes = true
+		mockProducer := mocks.NewAsyncProducer(t, cfg)
+		mockProducer.ExpectInputAndFail(assert.AnError)
+		asyncProducer := producer.New(context.Background(), mockProducer)
+		defer func() {
+			_ = asyncProducer.Close()
+		}()
+
+		err := New(context.Background(), &config.Config{DeadLetter: true}, asyncProducer).
+			Fail(&sarama.ConsumerMessage{Topic: exampleTopic}, assert.AnError)
+
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestPipeline_Wait(t *testing.T) {
+	p := New(context.Background(), &config.Config{RetryTopicDelayList: []time.Duration{time.Hour}}, nil)
+	retried := &sarama.ConsumerMessage{
+		Timestamp: time.Now(),
+		Headers:   []*sarama.RecordHeader{{Key: []byte(header.Attempt), Value: []byte("1")}},
+	}
+
+	assert.NoError(t, p.Wait(context.Background(), &sarama.ConsumerMessage{Timestamp: time.Now()}))
+	assert.NoError(t, p.Wait(context.Background(), &sarama.ConsumerMessage{
+		Timestamp: time.Now().Add(-2 * time.Hour),
+		Headers:   retried.Headers,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, p.Wait(ctx, retried))
+}
+
+func TestRedrive(t *testing.T) {
+	testCaseList := []struct {
+		name          string
+		recordCnt     int64
+		highWaterMark int64
+		limit         int
+		wantCnt       int
+	}{
+		{
+			name:          "Limit",
+			recordCnt:     3,
+			highWaterMark: 3,
+			limit:         2,
+			wantCnt:       2,
+		},
+		{
+			name:          "Partition end",
+			recordCnt:     3,
+			highWaterMark: 3,
+			wantCnt:       3,
+		},
+		{
+			name:          "Transaction marker at partition end",
+			recordCnt:     2,
+			highWaterMark: 3,
+			wantCnt:       2,
+		},
+	}
+
+	defer func(timeout time.Duration) {
+		idleTimeout = timeout
+	}(idleTimeout)
+	idleTimeout = time.Second
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newRedriveClient(t, tc.recordCnt, tc.highWaterMark)
+
+			producer := &producerStub{}
+			done := make(chan struct{})
+			var (
+				redrivenCnt int
+				err         error
+			)
+			go func() {
+				defer close(done)
+				redrivenCnt, err = Redrive(context.Background(), client, producer, redriveTopic, redriveGroupID, tc.limit)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				require.Fail(t, "redrive hangs")
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantCnt, redrivenCnt)
+			require.Len(t, producer.sentList, tc.wantCnt)
+			for _, sent := range producer.sentList {
+				assert.Equal(t, exampleTopic, sent.Topic)
+				assert.Equal(t, "id", producedHeader(sent, header.CorrelationID))
+				assert.Empty(t, producedHeader(sent, header.OriginalTopic))
+				assert.Empty(t, producedHeader(sent, header.Attempt))
+			}
+		})
+	}
+}
+
+const (
+	redriveTopic   = "example_topic.dlq"
+	redriveGroupID = "example.redrive"
+)
+
+// newRedriveClient returns client of broker serving dead letter partition of record count messages,
+// high water mark above record count leaves offsets without messages at partition end
+func newRedriveClient(t *testing.T, recordCnt, highWaterMark int64) sarama.Client {
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	// mock fetch response can't carry headers, records are built by hand
+	fetchResponse := &sarama.FetchResponse{Version: 8}
+	for offset := int64(0); offset < recordCnt; offset++ {
+		fetchResponse.AddRecord(redriveTopic, 0, nil, sarama.StringEncoder("example"), offset)
+	}
+	block := fetchResponse.GetBlock(redriveTopic, 0)
+	block.HighWaterMarkOffset = highWaterMark
+	for _, record := range block.RecordsSet[0].RecordBatch.Records {
+		record.Headers = []*sarama.RecordHeader{
+			{Key: []byte(header.OriginalTopic), Value: []byte(exampleTopic)},
+			{Key: []byte(header.Attempt), Value: []byte("2")},
+			{Key: []byte(header.CorrelationID), Value: []byte("id")},
+		}
+	}
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(redriveTopic, 0, broker.BrokerID()),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset(redriveTopic, 0, sarama.OffsetOldest, 0).
+			SetOffset(redriveTopic, 0, sarama.OffsetNewest, highWaterMark),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, redriveGroupID, broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset(redriveGroupID, redriveTopic, 0, -1, "", sarama.ErrNoError),
+		"OffsetCommitRequest": sarama.NewMockOffsetCommitResponse(t),
+		"FetchRequest":        sarama.NewMockWrapper(fetchResponse),
+	})
+
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V2_0_0_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return client
+}
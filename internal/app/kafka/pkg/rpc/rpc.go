@@ -0,0 +1,204 @@
+// Package rpc implements request/reply calls over Kafka topics
+package rpc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/header"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/producer"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/topic"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+var (
+	ErrTimeout          = errors.New("reply timeout")
+	ErrDeadlineExceeded = errors.New("deadline exceeded")
+)
+
+func NewClient(
+	ctx context.Context,
+	config *config.Config,
+	registry *topic.Registry,
+	producer producer.Producer,
+	consumer sarama.Consumer,
+) *Client {
+	_, span := tracer.Start(ctx, "internal.app.kafka.pkg.rpc.NewClient")
+	defer span.End()
+
+	return &Client{
+		config:   config,
+		logger:   logger.New("kafka.rpc"),
+		registry: registry,
+		producer: producer,
+		consumer: consumer,
+
+		pendingList: make(map[string]chan protocol.Response),
+	}
+}
+
+// Client sends requests and waits for replies, every client instance reads all reply partitions
+type Client struct {
+	config   *config.Config
+	logger   logger.Logger
+	registry *topic.Registry
+	producer producer.Producer
+	consumer sarama.Consumer
+	wg       sync.WaitGroup
+
+	partConsumerList []sarama.PartitionConsumer
+
+	mu          sync.Mutex
+	pendingList map[string]chan protocol.Response
+}
+
+// Start consumes every reply topic partition from the newest offset
+func (c *Client) Start(ctx context.Context) error {
+	_, span := tracer.Start(ctx, "internal.app.kafka.pkg.rpc.Client.Start")
+	defer span.End()
+
+	if err := c.registry.Check(c.config.RequestTopic, protocol.Request{}); err != nil {
+		return errors.Wrap(err, "checking request topic")
+	}
+	if err := c.registry.Check(c.config.ReplyTopic, protocol.Response{}); err != nil {
+		return errors.Wrap(err, "checking reply topic")
+	}
+
+	partitionList, err := c.consumer.Partitions(c.config.ReplyTopic)
+	if err != nil {
+		return errors.Wrap(err, "listing reply topic partitions")
+	}
+	for _, partition := range partitionList {
+		pc, err := c.consumer.ConsumePartition(c.config.ReplyTopic, partition, sarama.OffsetNewest)
+		if err != nil {
+			return errors.Wrapf(err, "consuming reply partition (%d)", partition)
+		}
+		c.partConsumerList = append(c.partConsumerList, pc)
+
+		c.wg.Add(2)
+		go func() {
+			defer c.wg.Done()
+			for msg := range pc.Messages() {
+				c.dispatch(msg)
+			}
+		}()
+		go func() {
+			defer c.wg.Done()
+			for err := range pc.Errors() {
+				c.logger.Error(err, "consuming replies")
+			}
+		}()
+	}
+
+	return nil
+}
+
+func (c *Client) Stop(ctx context.Context) {
+	_, span := tracer.Start(ctx, "internal.app.kafka.pkg.rpc.Client.Stop")
+	defer span.End()
+
+	for _, pc := range c.partConsumerList {
+		pc.AsyncClose()
+	}
+	c.wg.Wait()
+}
+
+// Sum returns sum of args calculated by Kafka service, waits for reply until context is done or reply timeout
+func (c *Client) Sum(ctx context.Context, args []int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "internal.app.kafka.pkg.rpc.Client.Sum")
+	defer span.End()
+
+	if c.config.ReplyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.ReplyTimeout)
+		defer cancel()
+	}
+
+	request := protocol.Request{
+		Message: protocol.Message{
+			Type: protocol.MessageTypeRequest,
+		},
+		Payload: args,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		request.Deadline = deadline
+	}
+	value, err := c.registry.Encode(c.config.RequestTopic, request)
+	if err != nil {
+		return 0, err
+	}
+
+	t, _ := c.registry.Topic(c.config.RequestTopic)
+	correlationID := uuid.NewString()
+	replyChan := make(chan protocol.Response, 1)
+	c.mu.Lock()
+	c.pendingList[correlationID] = replyChan
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pendingList, correlationID)
+		c.mu.Unlock()
+	}()
+
+	if _, _, err := c.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: c.config.RequestTopic,
+		Key:   sarama.StringEncoder(correlationID),
+		Value: sarama.ByteEncoder(value),
+		Headers: append(
+			[]sarama.RecordHeader{{Key: []byte(header.CorrelationID), Value: []byte(correlationID)}},
+			header.New(ctx, t.Message, t.Codec.Name(), c.config.ProducerID)...,
+		),
+	}); err != nil {
+		return 0, errors.Wrap(err, "sending request")
+	}
+
+	select {
+	case <-ctx.Done():
+		return 0, errors.Wrapf(ErrTimeout, "correlation id (%s): %v", correlationID, ctx.Err())
+	case response := <-replyChan:
+		switch {
+		case response.Type == protocol.MessageTypeError && response.Error == protocol.ErrorDeadlineExceeded:
+			return 0, errors.Wrapf(ErrDeadlineExceeded, "correlation id (%s)", correlationID)
+		case response.Type != protocol.MessageTypeResponse:
+			return 0, errors.Errorf("reply: received wrong message (%v)", response)
+		}
+
+		return response.Payload, nil
+	}
+}
+
+// dispatch passes reply to waiting request, replies to other clients are skipped
+func (c *Client) dispatch(msg *sarama.ConsumerMessage) {
+	correlationID := header.Get(msg.Headers, header.CorrelationID)
+
+	c.mu.Lock()
+	replyChan, ok := c.pendingList[correlationID]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	value, err := c.registry.DecodeMessage(
+		msg.Topic,
+		header.Get(msg.Headers, header.MessageType),
+		header.Get(msg.Headers, header.ContentType),
+		msg.Value,
+	)
+	if err != nil {
+		c.logger.Error(err, "decoding reply", "correlation id", correlationID)
+
+		return
+	}
+
+	select {
+	case replyChan <- value.(protocol.Response):
+	default:
+	}
+}
@@ -0,0 +1,151 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/header"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/topic"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+)
+
+const (
+	requestTopic = "example_request"
+	replyTopic   = "example_reply"
+)
+
+type producerStub struct {
+	sarama.SyncProducer
+	send func(msg *sarama.ProducerMessage) error
+}
+
+func (p *producerStub) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	return 0, 0, p.send(msg)
+}
+
+func TestClient_Sum(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func(reply func(correlationID string, response protocol.Response)) func(*sarama.ProducerMessage) error
+		wantResult int64
+		wantError  error
+	}{
+		{
+			name: "Success",
+			args: func(reply func(string, protocol.Response)) func(*sarama.ProducerMessage) error {
+				return func(msg *sarama.ProducerMessage) error {
+					correlationID := string(msg.Headers[0].Value)
+					reply("example", protocol.Response{
+						Message: protocol.Message{Type: protocol.MessageTypeResponse},
+						Payload: 7,
+					})
+					reply(correlationID, protocol.Response{
+						Message: protocol.Message{Type: protocol.MessageTypeResponse},
+						Payload: 6,
+					})
+
+					return nil
+				}
+			},
+			wantResult: 6,
+		},
+		{
+			name: "Deadline exceeded",
+			args: func(reply func(string, protocol.Response)) func(*sarama.ProducerMessage) error {
+				return func(msg *sarama.ProducerMessage) error {
+					reply(string(msg.Headers[0].Value), protocol.Response{
+						Message: protocol.Message{Type: protocol.MessageTypeError},
+						Error:   protocol.ErrorDeadlineExceeded,
+					})
+
+					return nil
+				}
+			},
+			wantError: ErrDeadlineExceeded,
+		},
+		{
+			name: "Timeout",
+			args: func(func(string, protocol.Response)) func(*sarama.ProducerMessage) error {
+				return func(*sarama.ProducerMessage) error {
+					return nil
+				}
+			},
+			wantError: ErrTimeout,
+		},
+		{
+			name: "Send error",
+			args: func(func(string, protocol.Response)) func(*sarama.ProducerMessage) error {
+				return func(*sarama.ProducerMessage) error {
+					return assert.AnError
+				}
+			},
+			wantError: assert.AnError,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			registry, err := topic.New([]config.TopicSpec{
+				{Name: requestTopic, Message: topic.MessageRequest, Codec: config.CodecGob},
+				{Name: replyTopic, Message: topic.MessageResponse, Codec: config.CodecJSON},
+			})
+			require.NoError(t, err)
+
+			consumer := mocks.NewConsumer(t, nil)
+			consumer.SetTopicMetadata(map[string][]int32{replyTopic: {0}})
+			pc := consumer.ExpectConsumePartition(replyTopic, 0, sarama.OffsetNewest)
+			reply := func(correlationID string, response protocol.Response) {
+				value, err := registry.Encode(replyTopic, response)
+				require.NoError(t, err)
+				pc.YieldMessage(&sarama.ConsumerMessage{
+					Value: value,
+					Headers: []*sarama.RecordHeader{
+						{Key: []byte(header.CorrelationID), Value: []byte(correlationID)},
+					},
+				})
+			}
+
+			c := NewClient(context.Background(), &config.Config{
+				RequestTopic: requestTopic,
+				ReplyTopic:   replyTopic,
+				ReplyTimeout: 50 * time.Millisecond,
+			}, registry, &producerStub{send: tc.args(reply)}, consumer)
+			require.NoError(t, c.Start(context.Background()))
+			defer c.Stop(context.Background())
+
+			result, err := c.Sum(context.Background(), []int64{1, 2, 3})
+			if tc.wantError != nil {
+				assert.ErrorIs(t, err, tc.wantError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, result)
+		})
+	}
+}
+
+func TestClient_Start(t *testing.T) {
+	t.Run("Topics disagree with message types", func(t *testing.T) {
+		registry, err := topic.New([]config.TopicSpec{
+			{Name: requestTopic, Message: topic.MessageResponse, Codec: config.CodecGob},
+			{Name: replyTopic, Message: topic.MessageResponse, Codec: config.CodecGob},
+		})
+		require.NoError(t, err)
+
+		c := NewClient(context.Background(), &config.Config{
+			RequestTopic: requestTopic,
+			ReplyTopic:   replyTopic,
+		}, registry, &producerStub{}, mocks.NewConsumer(t, nil))
+
+		assert.ErrorIs(t, c.Start(context.Background()), topic.ErrTypeMismatch)
+	})
+}
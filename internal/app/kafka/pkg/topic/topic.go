@@ -0,0 +1,194 @@
+// Package topic binds Kafka topics to message types and codecs
+package topic
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+)
+
+var (
+	ErrUnknownTopic = errors.New("unknown topic")
+	ErrTypeMismatch = errors.New("message type mismatch")
+	ErrUnknownCodec = errors.New("unknown codec")
+)
+
+const (
+	MessageRequest  = "request"
+	MessageResponse = "response"
+)
+
+var messageTypeList = map[string]reflect.Type{
+	MessageRequest:  reflect.TypeOf(protocol.Request{}),
+	MessageResponse: reflect.TypeOf(protocol.Response{}),
+}
+
+// Codec converts messages to record values and back
+type Codec interface {
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var codecList = map[string]Codec{
+	config.CodecGob:  gobCodec{},
+	config.CodecJSON: jsonCodec{},
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Name() string {
+	return config.CodecGob
+}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return config.CodecJSON
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// Topic is a topic bound to message type and codec
+type Topic struct {
+	Name    string
+	Message string
+	Type    reflect.Type
+	Codec   Codec
+}
+
+// Registry holds declared topics, producers and consumers must agree with their bindings
+type Registry struct {
+	topicList map[string]*Topic
+}
+
+func New(specList []config.TopicSpec) (*Registry, error) {
+	r := &Registry{
+		topicList: make(map[string]*Topic, len(specList)),
+	}
+	for _, spec := range specList {
+		messageType, ok := messageTypeList[spec.Message]
+		if !ok {
+			return nil, errors.Errorf("unknown message type (%s) of topic (%s)", spec.Message, spec.Name)
+		}
+		codec, ok := codecList[spec.Codec]
+		if !ok {
+			return nil, errors.Errorf("unknown codec (%s) of topic (%s)", spec.Codec, spec.Name)
+		}
+
+		r.topicList[spec.Name] = &Topic{
+			Name:    spec.Name,
+			Message: spec.Message,
+			Type:    messageType,
+			Codec:   codec,
+		}
+	}
+
+	return r, nil
+}
+
+// Topic returns declared topic by name
+func (r *Registry) Topic(name string) (*Topic, error) {
+	t, ok := r.topicList[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownTopic, "topic (%s)", name)
+	}
+
+	return t, nil
+}
+
+// Names returns sorted names of declared topics
+func (r *Registry) Names() []string {
+	result := make([]string, 0, len(r.topicList))
+	for name := range r.topicList {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// Check verifies that message type agrees with topic binding
+func (r *Registry) Check(name string, v any) error {
+	t, err := r.Topic(name)
+	if err != nil {
+		return err
+	}
+	if messageType := reflect.TypeOf(v); messageType != t.Type {
+		return errors.Wrapf(ErrTypeMismatch, "topic (%s) expects %s, got %v", name, t.Type, messageType)
+	}
+
+	return nil
+}
+
+// Encode checks message type and marshals it with topic codec
+func (r *Registry) Encode(name string, v any) ([]byte, error) {
+	if err := r.Check(name, v); err != nil {
+		return nil, err
+	}
+
+	t, _ := r.Topic(name)
+	data, err := t.Codec.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "encoding message of topic (%s)", name)
+	}
+
+	return data, nil
+}
+
+// Decode unmarshals record value of topic into message of bound type
+func (r *Registry) Decode(name string, data []byte) (any, error) {
+	return r.DecodeMessage(name, "", "", data)
+}
+
+// DecodeMessage unmarshals record value described by message type and content type headers,
+// message type must agree with topic binding, empty values fall back to topic binding
+func (r *Registry) DecodeMessage(name, messageType, contentType string, data []byte) (any, error) {
+	t, err := r.Topic(name)
+	if err != nil {
+		return nil, err
+	}
+	if messageType != "" && messageType != t.Message {
+		return nil, errors.Wrapf(ErrTypeMismatch, "topic (%s) expects %s, got %s", name, t.Message, messageType)
+	}
+	codec := t.Codec
+	if contentType != "" {
+		var ok bool
+		if codec, ok = codecList[contentType]; !ok {
+			return nil, errors.Wrapf(ErrUnknownCodec, "content type (%s) of topic (%s)", contentType, name)
+		}
+	}
+
+	v := reflect.New(t.Type)
+	if err := codec.Unmarshal(data, v.Interface()); err != nil {
+		return nil, errors.Wrapf(err, "decoding message of topic (%s)", name)
+	}
+
+	return v.Elem().Interface(), nil
+}
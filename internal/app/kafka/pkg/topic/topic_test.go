@@ -0,0 +1,171 @@
+package topic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+)
+
+func TestNew(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      []config.TopicSpec
+		wantError bool
+	}{
+		{
+			name: "Success",
+			args: []config.TopicSpec{
+				{Name: "a", Message: MessageRequest, Codec: config.CodecGob},
+				{Name: "b", Message: MessageResponse, Codec: config.CodecJSON},
+			},
+		},
+		{
+			name:      "Unknown message type",
+			args:      []config.TopicSpec{{Name: "a", Message: "example", Codec: config.CodecGob}},
+			wantError: true,
+		},
+		{
+			name:      "Unknown codec",
+			args:      []config.TopicSpec{{Name: "a", Message: MessageRequest, Codec: "example"}},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := New(tc.args)
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a", "b"}, result.Names())
+		})
+	}
+}
+
+func TestRegistry_Encode(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      func() (string, any)
+		wantError error
+	}{
+		{
+			name: "Gob",
+			args: func() (string, any) {
+				return "a", protocol.Request{Payload: []int64{1, 2, 3}}
+			},
+		},
+		{
+			name: "JSON",
+			args: func() (string, any) {
+				return "b", protocol.Response{Payload: 6}
+			},
+		},
+		{
+			name: "Type mismatch",
+			args: func() (string, any) {
+				return "a", protocol.Message{Type: protocol.MessageTypeRequest}
+			},
+			wantError: ErrTypeMismatch,
+		},
+		{
+			name: "Pointer type mismatch",
+			args: func() (string, any) {
+				return "a", &protocol.Request{}
+			},
+			wantError: ErrTypeMismatch,
+		},
+		{
+			name: "Unknown topic",
+			args: func() (string, any) {
+				return "example", protocol.Request{}
+			},
+			wantError: ErrUnknownTopic,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			registry, err := New([]config.TopicSpec{
+				{Name: "a", Message: MessageRequest, Codec: config.CodecGob},
+				{Name: "b", Message: MessageResponse, Codec: config.CodecJSON},
+			})
+			require.NoError(t, err)
+			name, value := tc.args()
+
+			data, err := registry.Encode(name, value)
+			if tc.wantError != nil {
+				assert.ErrorIs(t, err, tc.wantError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			result, err := registry.Decode(name, data)
+			require.NoError(t, err)
+			assert.Equal(t, value, result)
+		})
+	}
+}
+
+func TestRegistry_DecodeMessage(t *testing.T) {
+	registry, err := New([]config.TopicSpec{
+		{Name: "a", Message: MessageRequest, Codec: config.CodecGob},
+	})
+	require.NoError(t, err)
+	jsonData, err := json.Marshal(protocol.Request{Payload: []int64{1}})
+	require.NoError(t, err)
+
+	testCaseList := []struct {
+		name      string
+		args      func() (messageType, contentType string)
+		wantValue any
+		wantError error
+	}{
+		{
+			name: "Declared content type",
+			args: func() (string, string) {
+				return MessageRequest, config.CodecJSON
+			},
+			wantValue: protocol.Request{Payload: []int64{1}},
+		},
+		{
+			name: "Type mismatch",
+			args: func() (string, string) {
+				return MessageResponse, config.CodecJSON
+			},
+			wantError: ErrTypeMismatch,
+		},
+		{
+			name: "Unknown codec",
+			args: func() (string, string) {
+				return MessageRequest, "example"
+			},
+			wantError: ErrUnknownCodec,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			messageType, contentType := tc.args()
+
+			result, err := registry.DecodeMessage("a", messageType, contentType, jsonData)
+			if tc.wantError != nil {
+				assert.ErrorIs(t, err, tc.wantError)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantValue, result)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/provision"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+// provision creates missing topics at startup, drift of existing topics is only reported
+func (k *Kafka) provision(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "internal.app.kafka.Kafka.provision")
+	defer span.End()
+
+	specList, err := provision.Specs(k.config)
+	if err != nil {
+		return err
+	}
+	report, err := provision.Reconcile(ctx, k.admin, specList, k.config.TopicProvisionDryRun)
+	if err != nil {
+		return errors.Wrap(err, "reconciling topics")
+	}
+
+	for _, topic := range report.CreatedList {
+		k.logger.Info("topic created", "topic", topic, "dry run", k.config.TopicProvisionDryRun)
+	}
+	for _, drift := range report.DriftList {
+		k.logger.Error(provision.ErrDrift,
+			"topic", drift.Topic,
+			"setting", drift.Setting,
+			"want", drift.Want,
+			"got", drift.Got,
+		)
+	}
+	k.driftGauge.Set(int64(len(report.DriftList)))
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/header"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/producer"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/pkg/math"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+const (
+	payloadSize     = 3
+	payloadMaxDigit = 1024
+)
+
+// serveSum replies to sum request with correlation id of request, expired requests are refused
+func (k *Kafka) serveSum(ctx context.Context, msg *sarama.ConsumerMessage, value any) error {
+	ctx, span := tracer.Start(ctx, "internal.app.kafka.Kafka.serveSum")
+	defer span.End()
+
+	request, ok := value.(protocol.Request)
+	if !ok {
+		return errors.Errorf("sum request: received wrong message (%v)", value)
+	}
+	if request.Type != protocol.MessageTypeRequest {
+		return errors.Errorf("sum request: received wrong message (%v)", request)
+	}
+
+	response := protocol.Response{
+		Message: protocol.Message{
+			Type: protocol.MessageTypeResponse,
+		},
+	}
+	switch {
+	case !request.Deadline.IsZero() && !time.Now().Before(request.Deadline):
+		response.Type = protocol.MessageTypeError
+		response.Error = protocol.ErrorDeadlineExceeded
+	case len(request.Payload) > 0:
+		response.Payload = math.Sum(append([]int64(nil), request.Payload...)...)
+	}
+
+	reply, err := k.registry.Encode(k.config.ReplyTopic, response)
+	if err != nil {
+		return err
+	}
+	t, _ := k.registry.Topic(k.config.ReplyTopic)
+	correlationID := header.Get(msg.Headers, header.CorrelationID)
+	// reply is not awaited, delivery failures are counted by producer
+	if err := producer.Send(k.output(), &sarama.ProducerMessage{
+		Topic: k.config.ReplyTopic,
+		Key:   sarama.ByteEncoder(msg.Key),
+		Value: sarama.ByteEncoder(reply),
+		Headers: append(
+			[]sarama.RecordHeader{{Key: []byte(header.CorrelationID), Value: []byte(correlationID)}},
+			header.New(ctx, t.Message, t.Codec.Name(), k.config.ProducerID)...,
+		),
+	}); err != nil {
+		return errors.Wrap(err, "sending reply")
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/header"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/topic"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+func TestKafka_serveSum(t *testing.T) {
+	testCaseList := []struct {
+		name         string
+		args         any
+		wantResponse protocol.Response
+		wantError    bool
+	}{
+		{
+			name: "Success",
+			args: protocol.Request{
+				Message: protocol.Message{Type: protocol.MessageTypeRequest},
+				Payload: []int64{1, 2, 3},
+			},
+			wantResponse: protocol.Response{
+				Message: protocol.Message{Type: protocol.MessageTypeResponse},
+				Payload: 6,
+			},
+		},
+		{
+			name: "Empty payload",
+			args: protocol.Request{
+				Message: protocol.Message{Type: protocol.MessageTypeRequest},
+			},
+			wantResponse: protocol.Response{
+				Message: protocol.Message{Type: protocol.MessageTypeResponse},
+			},
+		},
+		{
+			name: "Deadline exceeded",
+			args: protocol.Request{
+				Message:  protocol.Message{Type: protocol.MessageTypeRequest},
+				Payload:  []int64{1, 2, 3},
+				Deadline: time.Now().Add(-time.Millisecond),
+			},
+			wantResponse: protocol.Response{
+				Message: protocol.Message{Type: protocol.MessageTypeError},
+				Error:   protocol.ErrorDeadlineExceeded,
+			},
+		},
+		{
+			name: "Wrong message",
+			args: protocol.Request{
+				Message: protocol.Message{Type: protocol.MessageTypeResponse},
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			k := New(context.Background(), &config.Config{ReplyTopic: topicB, ProducerID: "example"})
+			k.registry = newRegistry(t)
+			ctx, span := tracer.Start(context.Background(), "example")
+			producer := mocks.NewSyncProducer(t, nil)
+			k.producer = producer
+			if !tc.wantError {
+				producer.ExpectSendMessageWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+					assert.Equal(t, topicB, msg.Topic)
+					headerList := make([]*sarama.RecordHeader, 0, len(msg.Headers))
+					for i := range msg.Headers {
+						headerList = append(headerList, &msg.Headers[i])
+					}
+					assert.Equal(t, "example", header.Get(headerList, header.CorrelationID))
+					assert.Equal(t, topic.MessageResponse, header.Get(headerList, header.MessageType))
+					assert.Equal(t, config.CodecJSON, header.Get(headerList, header.ContentType))
+					assert.Equal(t, "example", header.Get(headerList, header.ProducerID))
+					assert.Equal(t,
+						span.SpanContext().TraceID,
+						tracer.SpanContextFromContext(header.Context(context.Background(), headerList)).TraceID,
+					)
+
+					value, err := msg.Value.Encode()
+					require.NoError(t, err)
+					response, err := k.registry.Decode(topicB, value)
+					require.NoError(t, err)
+					assert.Equal(t, tc.wantResponse, response)
+
+					return nil
+				})
+			}
+
+			err := k.serveSum(ctx, &sarama.ConsumerMessage{
+				Topic: topicA,
+				Headers: []*sarama.RecordHeader{
+					{Key: []byte(header.CorrelationID), Value: []byte("example")},
+				},
+			}, tc.args)
+			require.NoError(t, producer.Close())
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
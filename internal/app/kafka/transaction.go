@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/producer"
+)
+
+// output returns producer of consumed messages results, transactional one when configured
+func (k *Kafka) output() producer.Producer {
+	if k.txnProducer != nil {
+		return k.txnProducer
+	}
+
+	return k.producer
+}
+
+// transact runs processing of consumed message, in transactional mode messages produced by process
+// and consumed message offset are committed atomically, transactions are serialized across claims
+func (k *Kafka) transact(msg *sarama.ConsumerMessage, process func() error) error {
+	if k.txnProducer == nil {
+		return process()
+	}
+
+	k.txnMu.Lock()
+	defer k.txnMu.Unlock()
+
+	if err := k.txnProducer.BeginTxn(); err != nil {
+		return errors.Wrap(err, "beginning transaction")
+	}
+	if err := process(); err != nil {
+		return k.abort(errors.Wrap(err, "processing message"))
+	}
+	if err := k.txnProducer.AddMessageToTxn(msg, k.config.GroupID, nil); err != nil {
+		return k.abort(errors.Wrap(err, "adding offset to transaction"))
+	}
+	if err := k.txnProducer.CommitTxn(); err != nil {
+		return k.abort(errors.Wrap(err, "committing transaction"))
+	}
+	k.committedTxnCounter.Inc()
+
+	return nil
+}
+
+func (k *Kafka) abort(cause error) error {
+	k.abortedTxnCounter.Inc()
+	if k.txnProducer.TxnStatus()&sarama.ProducerTxnFlagInTransaction == 0 {
+		return cause
+	}
+	if err := k.txnProducer.AbortTxn(); err != nil {
+		return errors.Wrapf(cause, "aborting transaction: %v", err)
+	}
+
+	return cause
+}
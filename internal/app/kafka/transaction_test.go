@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/kirill-a-belov/test_task_framework/internal/app/kafka/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
+)
+
+func newTxnProducer(t *testing.T, cfg *config.Config) sarama.SyncProducer {
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetController(broker.BrokerID()).
+			SetLeader(topicB, 0, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorTransaction, cfg.TransactionalID, broker).
+			SetCoordinator(sarama.CoordinatorGroup, cfg.GroupID, broker),
+		"InitProducerIDRequest": sarama.NewMockInitProducerIDResponse(t).SetProducerID(1),
+		"AddPartitionsToTxnRequest": sarama.NewMockWrapper(&sarama.AddPartitionsToTxnResponse{
+			Errors: map[string][]*sarama.PartitionError{topicB: {{Partition: 0}}},
+		}),
+		"ProduceRequest":         sarama.NewMockProduceResponse(t),
+		"AddOffsetsToTxnRequest": sarama.NewMockWrapper(&sarama.AddOffsetsToTxnResponse{}),
+		"TxnOffsetCommitRequest": sarama.NewMockWrapper(&sarama.TxnOffsetCommitResponse{
+			Topics: map[string][]*sarama.PartitionError{topicA: {{Partition: 0}}},
+		}),
+		"EndTxnRequest": sarama.NewMockWrapper(&sarama.EndTxnResponse{}),
+	})
+
+	saramaConfig, err := cfg.TransactionalSaramaConfig()
+	require.NoError(t, err)
+	producer, err := sarama.NewSyncProducer([]string{broker.Addr()}, saramaConfig)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = producer.Close()
+	})
+
+	return producer
+}
+
+func TestKafka_transact(t *testing.T) {
+	value := &bytes.Buffer{}
+	require.NoError(t, gob.NewEncoder(value).Encode(protocol.Request{
+		Message: protocol.Message{
+			Type: protocol.MessageTypeRequest,
+		},
+		Payload: []int64{1, 2, 3},
+	}))
+	msg := &sarama.ConsumerMessage{Topic: topicA, Value: value.Bytes()}
+
+	testCaseList := []struct {
+		name          string
+		args          func(k *Kafka) func() error
+		wantCommitted int64
+		wantAborted   int64
+		wantError     bool
+	}{
+		{
+			name: "Reply and offset are committed",
+			args: func(k *Kafka) func() error {
+				return func() error {
+					return k.handle(context.Background(), msg)
+				}
+			},
+			wantCommitted: 1,
+		},
+		{
+			name: "Failed processing is aborted",
+			args: func(*Kafka) func() error {
+				return func() error {
+					return assert.AnError
+				}
+			},
+			wantAborted: 1,
+			wantError:   true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{
+				ReplyTopic:      topicB,
+				GroupID:         "example",
+				TransactionalID: "example",
+			}
+			k := New(context.Background(), cfg)
+			k.registry = newRegistry(t)
+			k.txnProducer = newTxnProducer(t, cfg)
+			committed, aborted := k.committedTxnCounter.Value(), k.abortedTxnCounter.Value()
+
+			err := k.transact(msg, tc.args(k))
+			if tc.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, committed+tc.wantCommitted, k.committedTxnCounter.Value())
+			assert.Equal(t, aborted+tc.wantAborted, k.abortedTxnCounter.Value())
+			assert.Equal(t, sarama.ProducerTxnFlagReady, k.txnProducer.TxnStatus())
+		})
+	}
+
+	t.Run("Non transactional", func(t *testing.T) {
+		k := New(context.Background(), &config.Config{})
+
+		assert.ErrorIs(t, k.transact(msg, func() error {
+			return assert.AnError
+		}), assert.AnError)
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/caarlos0/env"
 	"github.com/pkg/errors"
 
+	"github.com/kirill-a-belov/test_task_framework/internal/app/server/pkg/filter"
 	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
 )
 
@@ -15,6 +16,20 @@ type Config struct {
 	Port         int           `env:"SERVER_PORT"`
 	ConnPoolSize int           `env:"SERVER_CONN_POOL_SIZE"`
 	ConnTTL      time.Duration `env:"SERVER_CONN_TTL"`
+	KeepAlive    bool          `env:"SERVER_KEEP_ALIVE"`
+	// IdleTimeout is how long kept alive connection waits for next request, zero means connection TTL
+	IdleTimeout time.Duration `env:"SERVER_IDLE_TIMEOUT"`
+
+	AllowCIDRList        []string      `env:"SERVER_ALLOW_CIDR_LIST"`
+	DenyCIDRList         []string      `env:"SERVER_DENY_CIDR_LIST"`
+	FilterFile           string        `env:"SERVER_FILTER_FILE"`
+	FilterReloadInterval time.Duration `env:"SERVER_FILTER_RELOAD_INTERVAL" envDefault:"1s"`
+
+	ProxyProtocol        bool     `env:"SERVER_PROXY_PROTOCOL"`
+	TrustedProxyCIDRList []string `env:"SERVER_TRUSTED_PROXY_CIDR_LIST"`
+
+	// MetricsInterval is period of metrics logging, zero disables it
+	MetricsInterval time.Duration `env:"SERVER_METRICS_INTERVAL" envDefault:"1m"`
 }
 
 func (c *Config) validate() error {
@@ -30,6 +45,35 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid connextion TTL: %v", c.ConnPoolSize)
 	}
 
+	if c.IdleTimeout < 0 {
+		return fmt.Errorf("invalid idle timeout: %v", c.IdleTimeout)
+	}
+
+	if _, err := filter.ParseCIDRList(c.AllowCIDRList); err != nil {
+		return errors.Wrap(err, "invalid allow CIDR list")
+	}
+
+	if _, err := filter.ParseCIDRList(c.DenyCIDRList); err != nil {
+		return errors.Wrap(err, "invalid deny CIDR list")
+	}
+
+	if c.FilterFile != "" && c.FilterReloadInterval < 10*time.Millisecond {
+		return fmt.Errorf("invalid filter reload interval: %v", c.FilterReloadInterval)
+	}
+
+	trustedProxyList, err := filter.ParseCIDRList(c.TrustedProxyCIDRList)
+	if err != nil {
+		return errors.Wrap(err, "invalid trusted proxy CIDR list")
+	}
+
+	if c.ProxyProtocol && len(trustedProxyList) == 0 {
+		return errors.New("proxy protocol enabled without trusted proxy CIDR list")
+	}
+
+	if c.MetricsInterval < 0 {
+		return fmt.Errorf("invalid metrics interval: %v", c.MetricsInterval)
+	}
+
 	return nil
 }
 
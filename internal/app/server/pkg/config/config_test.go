@@ -76,6 +76,101 @@ func TestConfig_validate(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "Success with filter",
+			args: Config{
+				Port:                 1,
+				ConnPoolSize:         2,
+				ConnTTL:              time.Millisecond,
+				AllowCIDRList:        []string{"10.0.0.0/8"},
+				DenyCIDRList:         []string{"10.1.0.0/16", "2001:db8::/32"},
+				FilterFile:           "filter.json",
+				FilterReloadInterval: time.Second,
+			},
+			wantError: false,
+		},
+		{
+			name: "Invalid allow CIDR",
+			args: Config{
+				Port:          1,
+				ConnPoolSize:  2,
+				ConnTTL:       time.Millisecond,
+				AllowCIDRList: []string{"10.0.0.0"},
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid deny CIDR",
+			args: Config{
+				Port:         1,
+				ConnPoolSize: 2,
+				ConnTTL:      time.Millisecond,
+				DenyCIDRList: []string{"example"},
+			},
+			wantError: true,
+		},
+		{
+			name: "Filter reload interval too small",
+			args: Config{
+				Port:                 1,
+				ConnPoolSize:         2,
+				ConnTTL:              time.Millisecond,
+				FilterFile:           "filter.json",
+				FilterReloadInterval: time.Microsecond,
+			},
+			wantError: true,
+		},
+		{
+			name: "Success with proxy protocol",
+			args: Config{
+				Port:                 1,
+				ConnPoolSize:         2,
+				ConnTTL:              time.Millisecond,
+				ProxyProtocol:        true,
+				TrustedProxyCIDRList: []string{"10.0.0.0/8"},
+			},
+			wantError: false,
+		},
+		{
+			name: "Proxy protocol without trusted proxies",
+			args: Config{
+				Port:          1,
+				ConnPoolSize:  2,
+				ConnTTL:       time.Millisecond,
+				ProxyProtocol: true,
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid trusted proxy CIDR",
+			args: Config{
+				Port:                 1,
+				ConnPoolSize:         2,
+				ConnTTL:              time.Millisecond,
+				TrustedProxyCIDRList: []string{"example"},
+			},
+			wantError: true,
+		},
+		{
+			name: "Negative idle timeout",
+			args: Config{
+				Port:         1,
+				ConnPoolSize: 2,
+				ConnTTL:      time.Millisecond,
+				IdleTimeout:  -time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Negative metrics interval",
+			args: Config{
+				Port:            1,
+				ConnPoolSize:    2,
+				ConnTTL:         time.Millisecond,
+				MetricsInterval: -time.Second,
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tc := range testCaseList {
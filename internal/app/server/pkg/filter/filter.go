@@ -0,0 +1,127 @@
+// Package filter implements CIDR based connection filtering
+package filter
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+func New() *Filter {
+	return &Filter{}
+}
+
+type Filter struct {
+	mu        sync.RWMutex
+	allowList []*net.IPNet
+	denyList  []*net.IPNet
+}
+
+// Update replaces allow and deny lists, empty allow list means all addresses are allowed
+func (f *Filter) Update(allowList, denyList []string) error {
+	allowNetList, err := ParseCIDRList(allowList)
+	if err != nil {
+		return errors.Wrap(err, "parsing allow list")
+	}
+	denyNetList, err := ParseCIDRList(denyList)
+	if err != nil {
+		return errors.Wrap(err, "parsing deny list")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.allowList, f.denyList = allowNetList, denyNetList
+
+	return nil
+}
+
+type fileContent struct {
+	AllowList []string `json:"allow"`
+	DenyList  []string `json:"deny"`
+}
+
+// Load replaces allow and deny lists with JSON file content
+func (f *Filter) Load(data []byte) error {
+	var content fileContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return errors.Wrap(err, "decoding filter file")
+	}
+
+	return f.Update(content.AllowList, content.DenyList)
+}
+
+// Allowed reports whether connection from address may be served, deny list has priority
+func (f *Filter) Allowed(addr net.Addr) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if len(f.allowList) == 0 && len(f.denyList) == 0 {
+		return true
+	}
+
+	if addrIP(addr) == nil {
+		return false
+	}
+	if Contains(f.denyList, addr) {
+		return false
+	}
+
+	return len(f.allowList) == 0 || Contains(f.allowList, addr)
+}
+
+func ParseCIDRList(cidrList []string) ([]*net.IPNet, error) {
+	result := make([]*net.IPNet, 0, len(cidrList))
+	for _, cidr := range cidrList {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing CIDR (%s)", cidr)
+		}
+		result = append(result, ipNet)
+	}
+
+	return result, nil
+}
+
+// Contains reports whether address belongs to any of networks
+func Contains(netList []*net.IPNet, addr net.Addr) bool {
+	ip := addrIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range netList {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func addrIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		if a == nil {
+			return nil
+		}
+
+		return a.IP
+	case nil:
+		return nil
+	default:
+		host, _, err := net.SplitHostPort(a.String())
+		if err != nil {
+			return nil
+		}
+
+		return net.ParseIP(host)
+	}
+}
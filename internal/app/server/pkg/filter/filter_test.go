@@ -0,0 +1,120 @@
+package filter
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter_Allowed(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func() (*Filter, net.Addr)
+		wantResult bool
+	}{
+		{
+			name: "No rules",
+			args: func() (*Filter, net.Addr) {
+				return New(), nil
+			},
+			wantResult: true,
+		},
+		{
+			name: "Allowed by allow list",
+			args: func() (*Filter, net.Addr) {
+				f := New()
+				require.NoError(t, f.Update([]string{"10.0.0.0/8"}, nil))
+
+				return f, &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 1234}
+			},
+			wantResult: true,
+		},
+		{
+			name: "Not in allow list",
+			args: func() (*Filter, net.Addr) {
+				f := New()
+				require.NoError(t, f.Update([]string{"10.0.0.0/8"}, nil))
+
+				return f, &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 1234}
+			},
+			wantResult: false,
+		},
+		{
+			name: "Deny list priority",
+			args: func() (*Filter, net.Addr) {
+				f := New()
+				require.NoError(t, f.Update([]string{"10.0.0.0/8"}, []string{"10.1.0.0/16"}))
+
+				return f, &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 1234}
+			},
+			wantResult: false,
+		},
+		{
+			name: "Deny list only",
+			args: func() (*Filter, net.Addr) {
+				f := New()
+				require.NoError(t, f.Update(nil, []string{"2001:db8::/32"}))
+
+				return f, &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 1234}
+			},
+			wantResult: true,
+		},
+		{
+			name: "Unknown address with rules",
+			args: func() (*Filter, net.Addr) {
+				f := New()
+				require.NoError(t, f.Update(nil, []string{"10.0.0.0/8"}))
+
+				return f, nil
+			},
+			wantResult: false,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			f, addr := tc.args()
+
+			assert.Equal(t, tc.wantResult, f.Allowed(addr))
+		})
+	}
+}
+
+func TestFilter_Load(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      []byte
+		wantError bool
+	}{
+		{
+			name:      "Success",
+			args:      []byte(`{"allow": ["10.0.0.0/8"], "deny": ["10.1.0.0/16"]}`),
+			wantError: false,
+		},
+		{
+			name:      "Invalid JSON",
+			args:      []byte(`example`),
+			wantError: true,
+		},
+		{
+			name:      "Invalid CIDR",
+			args:      []byte(`{"deny": ["10.1.0.0"]}`),
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			err := New().Load(tc.args)
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
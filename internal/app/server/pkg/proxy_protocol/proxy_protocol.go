@@ -0,0 +1,194 @@
+// Package proxy_protocol implements HAProxy PROXY protocol v1/v2 header parsing
+package proxy_protocol
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var signatureV2 = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	signatureV1 = "PROXY "
+
+	maxHeaderV1Len = 107
+	headerV2Len    = 16
+
+	commandLocal = 0x0
+	commandProxy = 0x1
+
+	familyTCP4 = 0x11
+	familyTCP6 = 0x21
+
+	addrTCP4Len = 12
+	addrTCP6Len = 36
+)
+
+// Conn is a connection accepted from proxy which reports real client address
+type Conn struct {
+	net.Conn
+	reader     *bufio.Reader
+	remoteAddr net.Addr
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns client address announced by proxy
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// ProxyAddr returns address of the proxy itself
+func (c *Conn) ProxyAddr() net.Addr {
+	return c.Conn.RemoteAddr()
+}
+
+// Accept reads PROXY protocol header from connection within timeout
+func Accept(conn net.Conn, timeout time.Duration) (*Conn, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, errors.Wrap(err, "setting read deadline")
+	}
+
+	reader := bufio.NewReader(conn)
+	remoteAddr, err := readHeader(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading header")
+	}
+	if remoteAddr == nil {
+		remoteAddr = conn.RemoteAddr()
+	}
+
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, errors.Wrap(err, "resetting read deadline")
+	}
+
+	return &Conn{
+		Conn:       conn,
+		reader:     reader,
+		remoteAddr: remoteAddr,
+	}, nil
+}
+
+// readHeader returns nil address for LOCAL and UNKNOWN headers
+func readHeader(reader *bufio.Reader) (net.Addr, error) {
+	prefix, err := reader.Peek(len(signatureV1))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading signature")
+	}
+	if string(prefix) == signatureV1 {
+		return readHeaderV1(reader)
+	}
+
+	prefix, err = reader.Peek(len(signatureV2))
+	if err != nil {
+		return nil, errors.Wrap(err, "reading signature")
+	}
+	if bytes.Equal(prefix, signatureV2) {
+		return readHeaderV2(reader)
+	}
+
+	return nil, errors.New("missing PROXY protocol signature")
+}
+
+func readHeaderV1(reader *bufio.Reader) (net.Addr, error) {
+	line := make([]byte, 0, maxHeaderV1Len)
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, errors.Wrap(err, "reading v1 header")
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= maxHeaderV1Len {
+			return nil, errors.New("v1 header too long")
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("v1 header: invalid line ending")
+	}
+
+	fieldList := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fieldList) >= 2 && fieldList[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fieldList) != 6 {
+		return nil, errors.Errorf("v1 header: invalid fields count (%d)", len(fieldList))
+	}
+
+	ip := net.ParseIP(fieldList[2])
+	if ip == nil {
+		return nil, errors.Errorf("v1 header: invalid source address (%s)", fieldList[2])
+	}
+	switch {
+	case fieldList[1] == "TCP4" && ip.To4() == nil,
+		fieldList[1] == "TCP6" && ip.To4() != nil,
+		fieldList[1] != "TCP4" && fieldList[1] != "TCP6":
+		return nil, errors.Errorf("v1 header: invalid protocol (%s) for address (%s)", fieldList[1], ip)
+	}
+	port, err := strconv.ParseUint(fieldList[4], 10, 16)
+	if err != nil {
+		return nil, errors.Wrapf(err, "v1 header: invalid source port (%s)", fieldList[4])
+	}
+
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+func readHeaderV2(reader *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, headerV2Len)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, errors.Wrap(err, "reading v2 header")
+	}
+
+	verCmd, family := header[12], header[13]
+	if verCmd>>4 != 0x2 {
+		return nil, errors.Errorf("v2 header: invalid version (%d)", verCmd>>4)
+	}
+
+	addrBlock := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(reader, addrBlock); err != nil {
+		return nil, errors.Wrap(err, "reading v2 address block")
+	}
+
+	switch verCmd & 0xf {
+	case commandLocal:
+		return nil, nil
+	case commandProxy:
+	default:
+		return nil, errors.Errorf("v2 header: invalid command (%d)", verCmd&0xf)
+	}
+
+	switch family {
+	case familyTCP4:
+		if len(addrBlock) < addrTCP4Len {
+			return nil, errors.New("v2 header: short TCP4 address block")
+		}
+
+		return &net.TCPAddr{
+			IP:   net.IP(addrBlock[0:4]),
+			Port: int(binary.BigEndian.Uint16(addrBlock[8:10])),
+		}, nil
+	case familyTCP6:
+		if len(addrBlock) < addrTCP6Len {
+			return nil, errors.New("v2 header: short TCP6 address block")
+		}
+
+		return &net.TCPAddr{
+			IP:   net.IP(addrBlock[0:16]),
+			Port: int(binary.BigEndian.Uint16(addrBlock[32:34])),
+		}, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,102 @@
+package proxy_protocol
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccept(t *testing.T) {
+	const payload = "example payload"
+
+	testCaseList := []struct {
+		name       string
+		args       []byte
+		wantResult string
+		wantError  bool
+	}{
+		{
+			name:       "V1 TCP4",
+			args:       []byte("PROXY TCP4 192.168.0.1 10.0.0.1 56324 1234\r\n"),
+			wantResult: "192.168.0.1:56324",
+		},
+		{
+			name:       "V1 TCP6",
+			args:       []byte("PROXY TCP6 2001:db8::1 2001:db8::2 56324 1234\r\n"),
+			wantResult: "[2001:db8::1]:56324",
+		},
+		{
+			name:       "V1 unknown",
+			args:       []byte("PROXY UNKNOWN\r\n"),
+			wantResult: "pipe",
+		},
+		{
+			name:      "V1 protocol mismatch",
+			args:      []byte("PROXY TCP6 192.168.0.1 10.0.0.1 56324 1234\r\n"),
+			wantError: true,
+		},
+		{
+			name:      "V1 invalid port",
+			args:      []byte("PROXY TCP4 192.168.0.1 10.0.0.1 example 1234\r\n"),
+			wantError: true,
+		},
+		{
+			name: "V2 TCP4",
+			args: append(append([]byte{}, signatureV2...),
+				0x21, familyTCP4, 0x00, 0x0c,
+				192, 168, 0, 1, 10, 0, 0, 1, 0xdc, 0x04, 0x04, 0xd2),
+			wantResult: "192.168.0.1:56324",
+		},
+		{
+			name: "V2 local",
+			args: append(append([]byte{}, signatureV2...),
+				0x20, 0x00, 0x00, 0x00),
+			wantResult: "pipe",
+		},
+		{
+			name: "V2 invalid version",
+			args: append(append([]byte{}, signatureV2...),
+				0x11, familyTCP4, 0x00, 0x00),
+			wantError: true,
+		},
+		{
+			name:      "Missing header",
+			args:      []byte("example header"),
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			defer func() {
+				_ = server.Close()
+				_ = client.Close()
+			}()
+
+			go func() {
+				_, _ = client.Write(append(tc.args, payload...))
+			}()
+
+			conn, err := Accept(server, time.Second)
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, conn.RemoteAddr().String())
+			assert.Equal(t, server.RemoteAddr(), conn.ProxyAddr())
+
+			result := make([]byte, len(payload))
+			_, err = io.ReadFull(conn, result)
+			require.NoError(t, err)
+			assert.Equal(t, payload, string(result))
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -12,11 +13,15 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/kirill-a-belov/test_task_framework/internal/app/server/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/server/pkg/filter"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/server/pkg/proxy_protocol"
 	"github.com/kirill-a-belov/test_task_framework/internal/pkg/network"
 	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
 	"github.com/kirill-a-belov/test_task_framework/pkg/context_helper"
+	"github.com/kirill-a-belov/test_task_framework/pkg/file_watcher"
 	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
 	"github.com/kirill-a-belov/test_task_framework/pkg/math"
+	"github.com/kirill-a-belov/test_task_framework/pkg/metrics"
 	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
 )
 
@@ -28,6 +33,11 @@ func New(ctx context.Context, config *config.Config) *Server {
 		config:   config,
 		stopChan: make(chan struct{}),
 		logger:   logger.New("server"),
+		filter:   filter.New(),
+
+		rejectedConnCounter: metrics.NewCounter("server.conn.rejected"),
+		metricsExporter:     metrics.NewExporter(config.MetricsInterval),
+
 		listenerStarter: func() (net.Listener, error) {
 			return net.Listen(protocol.NetworkType, fmt.Sprintf(":%d", config.Port))
 		},
@@ -40,6 +50,12 @@ type Server struct {
 	logger   logger.Logger
 	connCnt  atomic.Int32
 
+	filter              *filter.Filter
+	trustedProxyList    []*net.IPNet
+	filterWatcher       *file_watcher.Watcher
+	rejectedConnCounter *metrics.Counter
+	metricsExporter     *metrics.Exporter
+
 	listener        net.Listener
 	listenerStarter func() (net.Listener, error)
 }
@@ -50,17 +66,37 @@ func (s *Server) Start(ctx context.Context) error {
 
 	s.logger.Info(fmt.Sprintf("config: %+v", *s.config))
 
+	if err := s.filter.Update(s.config.AllowCIDRList, s.config.DenyCIDRList); err != nil {
+		return errors.Wrap(err, "configuring connection filter")
+	}
+
 	var err error
+	if s.trustedProxyList, err = filter.ParseCIDRList(s.config.TrustedProxyCIDRList); err != nil {
+		return errors.Wrap(err, "parsing trusted proxy list")
+	}
+
 	if s.listener, err = s.listenerStarter(); err != nil {
 		return errors.Wrap(err, "start listener")
 	}
 
+	// watcher is started after listener, so failed start leaves no watcher goroutine behind
+	if s.config.FilterFile != "" {
+		watcher := file_watcher.New(s.config.FilterFile, s.config.FilterReloadInterval, s.filter.Load)
+		if err := watcher.Start(ctx); err != nil {
+			_ = s.listener.Close()
+
+			return errors.Wrap(err, "start connection filter watcher")
+		}
+		s.filterWatcher = watcher
+	}
+
+	s.metricsExporter.Start(ctx)
 	go s.processor(ctx, serv)
 
 	return nil
 }
 
-func (s *Server) processor(ctx context.Context, servFunc func(io.ReadWriter) error) {
+func (s *Server) processor(ctx context.Context, servFunc func(net.Conn) error) {
 	_, span := tracer.Start(ctx, "internal.app.server.Server.processor")
 	defer span.End()
 
@@ -86,31 +122,171 @@ func (s *Server) processor(ctx context.Context, servFunc func(io.ReadWriter) err
 
 				continue
 			}
+			// direct peers are filtered before taking pool slot, so denied peers can't starve allowed ones,
+			// proxied peers are known only after proxy protocol header is read
+			if !s.proxied(conn) && !s.admitted(conn.RemoteAddr()) {
+				_ = conn.Close()
+
+				continue
+			}
+			// pending proxy protocol handshakes take pool slots, so idle proxies can't pile up
 			s.connCnt.Add(1)
 			go func() {
-				defer func(conn net.Conn) {
-					_ = conn.Close()
-				}(conn)
 				defer s.connCnt.Add(-1)
-				if err := context_helper.RunWithTimeout(s.config.ConnTTL, func() error {
-					return servFunc(conn)
-				}); err != nil {
-					s.logger.Error(err, "connection serving")
-				}
+				s.handle(conn, servFunc)
 			}()
 		}
 	}
 }
 
+// handle admits proxied connection and serves it, proxy protocol header is read here
+// so slow proxies don't stall accepting
+func (s *Server) handle(conn net.Conn, servFunc func(net.Conn) error) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	if !s.proxied(conn) {
+		s.serve(conn, servFunc)
+
+		return
+	}
+
+	proxyConn, err := s.unwrapProxy(conn)
+	if err != nil {
+		s.logger.Error(err, "proxy protocol processing", "peer", conn.RemoteAddr())
+		s.rejectedConnCounter.Inc()
+
+		return
+	}
+	if !s.admitted(proxyConn.RemoteAddr()) {
+		return
+	}
+
+	s.serve(proxyConn, servFunc)
+}
+
+// admitted checks peer against connection filter, rejected peers are logged and counted
+func (s *Server) admitted(addr net.Addr) bool {
+	if s.filter.Allowed(addr) {
+		return true
+	}
+	s.logger.Info("connection rejected", "peer", addr)
+	s.rejectedConnCounter.Inc()
+
+	return false
+}
+
+// proxied reports whether connection comes from trusted proxy and starts with proxy protocol header
+func (s *Server) proxied(conn net.Conn) bool {
+	return s.config.ProxyProtocol && filter.Contains(s.trustedProxyList, conn.RemoteAddr())
+}
+
+// serve handles connection requests, with keep alive next request must start within idle timeout
+// and every request must be served within connection TTL
+func (s *Server) serve(conn net.Conn, servFunc func(net.Conn) error) {
+	for servedCnt := 0; ; servedCnt++ {
+		requestConn := conn
+		if servedCnt > 0 {
+			var ok bool
+			if requestConn, ok = s.awaitRequest(conn); !ok {
+				return
+			}
+		}
+
+		if err := context_helper.RunWithTimeout(s.config.ConnTTL, func() error {
+			return servFunc(requestConn)
+		}); err != nil {
+			if servedCnt == 0 || !errors.Is(err, io.EOF) {
+				s.logger.Error(err, "connection serving", "peer", conn.RemoteAddr())
+			}
+
+			return
+		}
+
+		if !s.config.KeepAlive {
+			return
+		}
+		select {
+		case <-s.stopChan:
+			return
+		default:
+		}
+	}
+}
+
+// awaitRequest waits for first byte of next request on kept alive connection,
+// idle peer or closed connection is a normal close, so it is logged only on unexpected errors
+func (s *Server) awaitRequest(conn net.Conn) (net.Conn, bool) {
+	idleTimeout := s.config.IdleTimeout
+	if idleTimeout == 0 {
+		idleTimeout = s.config.ConnTTL
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(idleTimeout)); err != nil {
+		s.logger.Error(err, "setting idle deadline", "peer", conn.RemoteAddr())
+
+		return nil, false
+	}
+
+	first := make([]byte, 1)
+	if _, err := io.ReadFull(conn, first); err != nil {
+		var netErr net.Error
+		if !errors.Is(err, io.EOF) && !(errors.As(err, &netErr) && netErr.Timeout()) {
+			s.logger.Error(err, "waiting for request", "peer", conn.RemoteAddr())
+		}
+
+		return nil, false
+	}
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		s.logger.Error(err, "resetting idle deadline", "peer", conn.RemoteAddr())
+
+		return nil, false
+	}
+
+	return &prefixedConn{
+		Conn:   conn,
+		reader: io.MultiReader(bytes.NewReader(first), conn),
+	}, true
+}
+
+// prefixedConn returns bytes read ahead before the rest of connection data
+type prefixedConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (c *prefixedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// unwrapProxy replaces proxy address with client one for connections from trusted proxies
+func (s *Server) unwrapProxy(conn net.Conn) (net.Conn, error) {
+	if !s.proxied(conn) {
+		return conn, nil
+	}
+
+	proxyConn, err := proxy_protocol.Accept(conn, s.config.ConnTTL)
+	if err != nil {
+		return conn, errors.Wrap(err, "accepting proxied connection")
+	}
+
+	return proxyConn, nil
+}
+
 func (s *Server) Stop(ctx context.Context) {
 	_, span := tracer.Start(ctx, "internal.app.server.Server.Stop")
 	defer span.End()
 
+	if s.filterWatcher != nil {
+		s.filterWatcher.Stop(ctx)
+	}
 	_ = s.listener.Close()
 	close(s.stopChan)
+	s.metricsExporter.Stop(ctx)
 }
 
-func serv(conn io.ReadWriter) error {
+// serv serves single request, connection remote address is client one, also behind proxy
+func serv(conn net.Conn) error {
 	ctx, span := tracer.Start(context.Background(), "internal.app.server.Server.serv")
 	defer span.End()
 
@@ -119,16 +295,45 @@ func serv(conn io.ReadWriter) error {
 		return errors.Wrap(err, "receiving server request")
 	}
 	if request.Type != protocol.MessageTypeRequest {
-		return errors.Errorf("server requrest: received wrong message (%v)", request)
+		return errors.Errorf("server requrest: received wrong message (%v) from (%s)", request, conn.RemoteAddr())
 	}
-	if err := network.Send(ctx, conn, protocol.Response{
+	if expired(request) {
+		return refuseExpired(ctx, conn)
+	}
+
+	response := protocol.Response{
 		Message: protocol.Message{
 			Type: protocol.MessageTypeResponse,
 		},
-		Payload: math.Sum(request.Payload...),
-	}); err != nil {
+	}
+	// sum of empty payload is zero, math.Sum panics on it
+	if len(request.Payload) > 0 {
+		response.Payload = math.Sum(request.Payload...)
+	}
+	if err := network.Send(ctx, conn, response); err != nil {
 		return errors.Wrap(err, "sending response")
 	}
 
 	return nil
 }
+
+// expired reports whether client has already abandoned request
+func expired(request protocol.Request) bool {
+	return !request.Deadline.IsZero() && !time.Now().Before(request.Deadline)
+}
+
+// refuseExpired answers expired request with deadline exceeded error
+func refuseExpired(ctx context.Context, conn io.ReadWriter) error {
+	metrics.NewCounter("server.request.expired").Inc()
+
+	if err := network.Send(ctx, conn, protocol.Response{
+		Message: protocol.Message{
+			Type: protocol.MessageTypeError,
+		},
+		Error: protocol.ErrorDeadlineExceeded,
+	}); err != nil {
+		return errors.Wrap(err, "sending deadline exceeded response")
+	}
+
+	return nil
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/gob"
 	"io"
 	"net"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -16,52 +18,89 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/kirill-a-belov/test_task_framework/internal/app/server/pkg/config"
+	"github.com/kirill-a-belov/test_task_framework/internal/app/server/pkg/filter"
+	"github.com/kirill-a-belov/test_task_framework/internal/pkg/network"
 	"github.com/kirill-a-belov/test_task_framework/internal/pkg/protocol"
 	"github.com/kirill-a-belov/test_task_framework/pkg/test_helper"
 )
 
 func TestServer_Start(t *testing.T) {
+	filterFile := filepath.Join(t.TempDir(), "filter.json")
+	require.NoError(t, os.WriteFile(filterFile, []byte(`{}`), 0o600))
+
 	testCaseList := []struct {
-		name      string
-		args      func() *listenerStarterMock
-		wantError bool
+		name       string
+		args       func() (*config.Config, *listenerStarterMock)
+		wantError  bool
+		wantClosed bool
 	}{
 		{
 			name: "Success",
-			args: func() *listenerStarterMock {
+			args: func() (*config.Config, *listenerStarterMock) {
 				lsm := &listenerStarterMock{}
 				lsm.On("mockFunc").Return(&net.TCPListener{}, nil)
 
-				return lsm
+				return &config.Config{}, lsm
 			},
 			wantError: false,
 		},
 		{
 			name: "Listen start failed",
-			args: func() *listenerStarterMock {
+			args: func() (*config.Config, *listenerStarterMock) {
+				lsm := &listenerStarterMock{}
+				lsm.On("mockFunc").Return(&net.TCPListener{}, errors.New("example error"))
+
+				return &config.Config{}, lsm
+			},
+			wantError: true,
+		},
+		{
+			name: "Listen start failed with filter file",
+			args: func() (*config.Config, *listenerStarterMock) {
 				lsm := &listenerStarterMock{}
 				lsm.On("mockFunc").Return(&net.TCPListener{}, errors.New("example error"))
 
-				return lsm
+				return &config.Config{
+					FilterFile:           filterFile,
+					FilterReloadInterval: time.Second,
+				}, lsm
 			},
 			wantError: true,
 		},
+		{
+			name: "Filter file loading failed",
+			args: func() (*config.Config, *listenerStarterMock) {
+				lsm := &listenerStarterMock{}
+				lsm.On("mockFunc").Return(&listenerStub{}, nil)
+
+				return &config.Config{
+					FilterFile:           filepath.Join(t.TempDir(), "missing.json"),
+					FilterReloadInterval: time.Second,
+				}, lsm
+			},
+			wantError:  true,
+			wantClosed: true,
+		},
 	}
 
 	for _, tc := range testCaseList {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx := context.Background()
-			s := New(ctx, &config.Config{})
-			s.listenerStarter = tc.args().mockFunc
+			cfg, lsm := tc.args()
+			s := New(ctx, cfg)
+			s.listenerStarter = lsm.mockFunc
 
 			err := s.Start(ctx)
-			defer s.Stop(ctx)
-
 			if tc.wantError {
 				assert.Error(t, err)
+				assert.Nil(t, s.filterWatcher)
+				if tc.wantClosed {
+					assert.True(t, s.listener.(*listenerStub).closed)
+				}
 
 				return
 			}
+			defer s.Stop(ctx)
 
 			assert.NoError(t, err)
 		})
@@ -81,12 +120,12 @@ func (lsm *listenerStarterMock) mockFunc() (net.Listener, error) {
 func TestServer_processor(t *testing.T) {
 	testCaseList := []struct {
 		name string
-		args func() (*Server, func(io.ReadWriter) error)
+		args func() (*Server, func(net.Conn) error)
 	}{
 		{
 			name: "Regular stop",
-			args: func() (*Server, func(io.ReadWriter) error) {
-				f := func(writer io.ReadWriter) error {
+			args: func() (*Server, func(net.Conn) error) {
+				f := func(net.Conn) error {
 					return nil
 				}
 
@@ -104,9 +143,10 @@ func TestServer_processor(t *testing.T) {
 		},
 		{
 			name: "Conn limit exceeded",
-			args: func() (*Server, func(io.ReadWriter) error) {
-				f := func(writer io.ReadWriter) error {
-					time.Sleep(time.Second)
+			args: func() (*Server, func(net.Conn) error) {
+				// occupies pool slot for the test, finishes within TTL to not log errors after it
+				f := func(net.Conn) error {
+					time.Sleep(500 * time.Millisecond)
 
 					return nil
 				}
@@ -126,8 +166,8 @@ func TestServer_processor(t *testing.T) {
 		},
 		{
 			name: "Serv func error",
-			args: func() (*Server, func(io.ReadWriter) error) {
-				f := func(writer io.ReadWriter) error {
+			args: func() (*Server, func(net.Conn) error) {
+				f := func(net.Conn) error {
 					return errors.New("example error")
 				}
 
@@ -141,6 +181,29 @@ func TestServer_processor(t *testing.T) {
 				s.logger = loggerMock
 				s.listener = &listenerStub{}
 
+				return s, f
+			},
+		},
+		{
+			name: "Rejected connection",
+			args: func() (*Server, func(net.Conn) error) {
+				f := func(net.Conn) error {
+					t.Error("rejected connection served")
+
+					return nil
+				}
+
+				loggerMock := &test_helper.LoggerMock{}
+				loggerMock.On("Info", mock.Anything)
+
+				s := New(context.Background(), &config.Config{
+					ConnTTL:      time.Second,
+					ConnPoolSize: 1,
+				})
+				s.logger = loggerMock
+				s.listener = &listenerStub{}
+				require.NoError(t, s.filter.Update(nil, []string{"10.0.0.0/8"}))
+
 				return s, f
 			},
 		},
@@ -166,6 +229,353 @@ func TestServer_processor(t *testing.T) {
 	}
 }
 
+func TestServer_processor_idleProxy(t *testing.T) {
+	listener, err := net.Listen(protocol.NetworkType, "127.0.0.1:0")
+	require.NoError(t, err)
+
+	loggerMock := &test_helper.LoggerMock{}
+	loggerMock.On("Info", mock.Anything)
+	loggerMock.On("Error", mock.Anything, mock.Anything)
+
+	ctx := context.Background()
+	s := New(ctx, &config.Config{
+		ConnTTL:              time.Second,
+		ConnPoolSize:         10,
+		ProxyProtocol:        true,
+		TrustedProxyCIDRList: []string{"127.0.0.0/8"},
+	})
+	s.logger = loggerMock
+	s.listener = listener
+	s.trustedProxyList, err = filter.ParseCIDRList(s.config.TrustedProxyCIDRList)
+	require.NoError(t, err)
+	go s.processor(ctx, serv)
+	defer s.Stop(ctx)
+
+	// idle proxy connection never sends header
+	idleConn, err := net.Dial(protocol.NetworkType, listener.Addr().String())
+	require.NoError(t, err)
+	defer func() {
+		_ = idleConn.Close()
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	conn, err := net.Dial(protocol.NetworkType, listener.Addr().String())
+	require.NoError(t, err)
+	defer func() {
+		_ = conn.Close()
+	}()
+	start := time.Now()
+	_, err = conn.Write([]byte("PROXY TCP4 192.168.0.1 127.0.0.1 56324 1234\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, network.Send(ctx, conn, protocol.Request{
+		Message: protocol.Message{
+			Type: protocol.MessageTypeRequest,
+		},
+		Payload: []int64{1, 2, 3},
+	}))
+	response, err := network.Receive[protocol.Response](ctx, conn)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(6), response.Payload)
+	assert.Less(t, time.Since(start), s.config.ConnTTL/2)
+}
+
+func TestServer_processor_proxiedAddress(t *testing.T) {
+	listener, err := net.Listen(protocol.NetworkType, "127.0.0.1:0")
+	require.NoError(t, err)
+
+	loggerMock := &test_helper.LoggerMock{}
+	loggerMock.On("Info", mock.Anything)
+	loggerMock.On("Error", mock.Anything, mock.Anything)
+
+	ctx := context.Background()
+	s := New(ctx, &config.Config{
+		ConnTTL:              time.Second,
+		ConnPoolSize:         1,
+		KeepAlive:            true,
+		ProxyProtocol:        true,
+		TrustedProxyCIDRList: []string{"127.0.0.0/8"},
+	})
+	s.logger = loggerMock
+	s.listener = listener
+	s.trustedProxyList, err = filter.ParseCIDRList(s.config.TrustedProxyCIDRList)
+	require.NoError(t, err)
+	peerChan := make(chan string, 2)
+	go s.processor(ctx, func(conn net.Conn) error {
+		peerChan <- conn.RemoteAddr().String()
+
+		return serv(conn)
+	})
+	defer s.Stop(ctx)
+
+	conn, err := net.Dial(protocol.NetworkType, listener.Addr().String())
+	require.NoError(t, err)
+	defer func() {
+		_ = conn.Close()
+	}()
+	_, err = conn.Write([]byte("PROXY TCP4 192.168.0.1 127.0.0.1 56324 1234\r\n"))
+	require.NoError(t, err)
+
+	// kept alive connection, second request is read ahead by idle wait
+	for i := 0; i < 2; i++ {
+		require.NoError(t, network.Send(ctx, conn, protocol.Request{
+			Message: protocol.Message{
+				Type: protocol.MessageTypeRequest,
+			},
+			Payload: []int64{1, 2, 3},
+		}))
+		_, err = network.Receive[protocol.Response](ctx, conn)
+		require.NoError(t, err)
+
+		assert.Equal(t, "192.168.0.1:56324", <-peerChan)
+	}
+}
+
+func TestServer_processor_deniedPeer(t *testing.T) {
+	listener, err := net.Listen(protocol.NetworkType, "127.0.0.1:0")
+	require.NoError(t, err)
+
+	loggerMock := &test_helper.LoggerMock{}
+	loggerMock.On("Info", mock.Anything)
+	// accept error after stop
+	loggerMock.On("Error", mock.Anything, mock.Anything)
+
+	ctx := context.Background()
+	s := New(ctx, &config.Config{
+		ConnTTL:      time.Second,
+		ConnPoolSize: 1,
+	})
+	s.logger = loggerMock
+	s.listener = listener
+	require.NoError(t, s.filter.Update(nil, []string{"127.0.0.2/32"}))
+	go s.processor(ctx, func(net.Conn) error {
+		t.Error("denied connection served")
+
+		return nil
+	})
+	defer s.Stop(ctx)
+
+	// burst of denied peers, pool of one slot would make accept loop wait if they took it
+	dialer := net.Dialer{LocalAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.2")}}
+	connList := make([]net.Conn, 0, 5)
+	for i := 0; i < cap(connList); i++ {
+		conn, err := dialer.Dial(protocol.NetworkType, listener.Addr().String())
+		require.NoError(t, err)
+		defer func() {
+			_ = conn.Close()
+		}()
+		connList = append(connList, conn)
+	}
+
+	start := time.Now()
+	for _, conn := range connList {
+		_, err = conn.Read(make([]byte, 1))
+		assert.ErrorIs(t, err, io.EOF)
+	}
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	assert.Zero(t, s.connCnt.Load())
+}
+
+func TestServer_serve(t *testing.T) {
+	testCaseList := []struct {
+		name          string
+		args          func() (*Server, net.Conn, func(net.Conn) error)
+		wantCallCount int
+	}{
+		{
+			name: "Single request",
+			args: func() (*Server, net.Conn, func(net.Conn) error) {
+				s := New(context.Background(), &config.Config{
+					ConnTTL: time.Second,
+				})
+				s.logger = &test_helper.LoggerMock{}
+
+				return s, &net.TCPConn{}, func(net.Conn) error {
+					return nil
+				}
+			},
+			wantCallCount: 1,
+		},
+		{
+			name: "Keep alive until EOF",
+			args: func() (*Server, net.Conn, func(net.Conn) error) {
+				s := New(context.Background(), &config.Config{
+					ConnTTL:   time.Second,
+					KeepAlive: true,
+				})
+				s.logger = &test_helper.LoggerMock{}
+
+				serverConn, clientConn := net.Pipe()
+				go func() {
+					// first byte of each next request
+					_, _ = clientConn.Write([]byte{1, 1})
+				}()
+
+				callCount := 0
+
+				return s, serverConn, func(net.Conn) error {
+					callCount++
+					if callCount == 3 {
+						return errors.Wrap(io.EOF, "receiving server request")
+					}
+
+					return nil
+				}
+			},
+			wantCallCount: 3,
+		},
+		{
+			name: "Keep alive error",
+			args: func() (*Server, net.Conn, func(net.Conn) error) {
+				loggerMock := &test_helper.LoggerMock{}
+				loggerMock.On("Error", mock.Anything, mock.Anything).Once()
+
+				s := New(context.Background(), &config.Config{
+					ConnTTL:   time.Second,
+					KeepAlive: true,
+				})
+				s.logger = loggerMock
+
+				return s, &net.TCPConn{}, func(net.Conn) error {
+					return errors.New("example error")
+				}
+			},
+			wantCallCount: 1,
+		},
+		{
+			name: "Keep alive idle timeout",
+			args: func() (*Server, net.Conn, func(net.Conn) error) {
+				s := New(context.Background(), &config.Config{
+					ConnTTL:     time.Second,
+					KeepAlive:   true,
+					IdleTimeout: 10 * time.Millisecond,
+				})
+				s.logger = &test_helper.LoggerMock{}
+
+				serverConn, _ := net.Pipe()
+
+				return s, serverConn, func(net.Conn) error {
+					return nil
+				}
+			},
+			wantCallCount: 1,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			testServer, conn, testFunc := tc.args()
+
+			callCount := 0
+			testServer.serve(conn, func(rw net.Conn) error {
+				callCount++
+
+				return testFunc(rw)
+			})
+
+			assert.Equal(t, tc.wantCallCount, callCount)
+		})
+	}
+}
+
+func TestServer_unwrapProxy(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func() (*config.Config, []byte)
+		wantResult string
+		wantError  bool
+	}{
+		{
+			name: "Proxy protocol disabled",
+			args: func() (*config.Config, []byte) {
+				return &config.Config{
+					ConnTTL:              time.Second,
+					TrustedProxyCIDRList: []string{"127.0.0.0/8"},
+				}, nil
+			},
+			wantResult: "127.0.0.1",
+		},
+		{
+			name: "Trusted proxy",
+			args: func() (*config.Config, []byte) {
+				return &config.Config{
+					ConnTTL:              time.Second,
+					ProxyProtocol:        true,
+					TrustedProxyCIDRList: []string{"127.0.0.0/8"},
+				}, []byte("PROXY TCP4 192.168.0.1 127.0.0.1 56324 1234\r\n")
+			},
+			wantResult: "192.168.0.1",
+		},
+		{
+			name: "Untrusted proxy",
+			args: func() (*config.Config, []byte) {
+				return &config.Config{
+					ConnTTL:              time.Second,
+					ProxyProtocol:        true,
+					TrustedProxyCIDRList: []string{"10.0.0.0/8"},
+				}, []byte("PROXY TCP4 192.168.0.1 127.0.0.1 56324 1234\r\n")
+			},
+			wantResult: "127.0.0.1",
+		},
+		{
+			name: "Trusted proxy without header",
+			args: func() (*config.Config, []byte) {
+				return &config.Config{
+					ConnTTL:              time.Second,
+					ProxyProtocol:        true,
+					TrustedProxyCIDRList: []string{"127.0.0.0/8"},
+				}, []byte("example header")
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, header := tc.args()
+
+			ctx := context.Background()
+			s := New(ctx, cfg)
+			var err error
+			s.trustedProxyList, err = filter.ParseCIDRList(cfg.TrustedProxyCIDRList)
+			require.NoError(t, err)
+
+			listener, err := net.Listen(protocol.NetworkType, "127.0.0.1:0")
+			require.NoError(t, err)
+			defer func() {
+				_ = listener.Close()
+			}()
+
+			go func() {
+				clientConn, err := net.Dial(protocol.NetworkType, listener.Addr().String())
+				if err != nil {
+					return
+				}
+				defer func() {
+					_ = clientConn.Close()
+				}()
+				_, _ = clientConn.Write(header)
+			}()
+
+			conn, err := listener.Accept()
+			require.NoError(t, err)
+			defer func() {
+				_ = conn.Close()
+			}()
+
+			result, err := s.unwrapProxy(conn)
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantResult, result.RemoteAddr().(*net.TCPAddr).IP.String())
+		})
+	}
+}
+
 func TestServer_Stop(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		ctx := context.Background()
@@ -182,12 +592,12 @@ func TestServer_Stop(t *testing.T) {
 func Test_serv(t *testing.T) {
 	testCaseList := []struct {
 		name      string
-		args      func() io.ReadWriter
+		args      func() net.Conn
 		wantError bool
 	}{
 		{
 			name: "Success",
-			args: func() io.ReadWriter {
+			args: func() net.Conn {
 				request := protocol.Request{
 					Message: protocol.Message{
 						Type: protocol.MessageTypeRequest,
@@ -205,19 +615,19 @@ func Test_serv(t *testing.T) {
 					}).
 					Return(1000, nil)
 
-				rwm.On("Write", []byte{0x2f, 0xff, 0x85, 0x3, 0x1, 0x1, 0x8, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x1, 0xff, 0x86, 0x0, 0x1, 0x2, 0x1, 0x7, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x1, 0xff, 0x82, 0x0, 0x1, 0x7, 0x50, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x1, 0x4, 0x0, 0x0, 0x0}).
+				rwm.On("Write", []byte{0x39, 0xff, 0x87, 0x3, 0x1, 0x1, 0x8, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x1, 0xff, 0x88, 0x0, 0x1, 0x3, 0x1, 0x7, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x1, 0xff, 0x82, 0x0, 0x1, 0x7, 0x50, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x1, 0x4, 0x0, 0x1, 0x5, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x1, 0xc, 0x0, 0x0, 0x0}).
 					Return(100, nil)
 				rwm.On("Write", []byte{0x1e, 0xff, 0x81, 0x3, 0x1, 0x1, 0x7, 0x4d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x1, 0xff, 0x82, 0x0, 0x1, 0x1, 0x1, 0x4, 0x54, 0x79, 0x70, 0x65, 0x1, 0xc, 0x0, 0x0, 0x0}).
 					Return(100, nil)
-				rwm.On("Write", []byte{0x11, 0xff, 0x86, 0x1, 0x1, 0x8, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x0, 0x1, 0xc, 0x0}).
+				rwm.On("Write", []byte{0x11, 0xff, 0x88, 0x1, 0x1, 0x8, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x0, 0x1, 0xc, 0x0}).
 					Return(100, nil)
 
-				return rwm
+				return &connStub{rw: rwm}
 			},
 		},
 		{
 			name: "Wrong msg type",
-			args: func() io.ReadWriter {
+			args: func() net.Conn {
 				request := protocol.Request{
 					Message: protocol.Message{
 						Type: protocol.MessageTypeResponse,
@@ -235,18 +645,18 @@ func Test_serv(t *testing.T) {
 					}).
 					Return(1000, nil)
 
-				return rwm
+				return &connStub{rw: rwm}
 			},
 			wantError: true,
 		},
 		{
 			name: "Conn error",
-			args: func() io.ReadWriter {
+			args: func() net.Conn {
 				rwm := &test_helper.ReadWriterMock{}
 				rwm.On("Read", mock.Anything).
 					Return(1000, errors.New("example error"))
 
-				return rwm
+				return &connStub{rw: rwm}
 			},
 			wantError: true,
 		},
@@ -266,14 +676,114 @@ func Test_serv(t *testing.T) {
 	}
 }
 
+func Test_serv_deadline(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		args     time.Time
+		wantType protocol.MessageType
+	}{
+		{
+			name:     "No deadline",
+			wantType: protocol.MessageTypeResponse,
+		},
+		{
+			name:     "Deadline ahead",
+			args:     time.Now().Add(time.Hour),
+			wantType: protocol.MessageTypeResponse,
+		},
+		{
+			name:     "Deadline passed",
+			args:     time.Now().Add(-time.Millisecond),
+			wantType: protocol.MessageTypeError,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			clientConn, serverConn := net.Pipe()
+			defer func() {
+				_ = clientConn.Close()
+			}()
+			errChan := make(chan error, 1)
+			go func() {
+				errChan <- serv(serverConn)
+			}()
+
+			ctx := context.Background()
+			require.NoError(t, network.Send(ctx, clientConn, protocol.Request{
+				Message: protocol.Message{
+					Type: protocol.MessageTypeRequest,
+				},
+				Payload:  []int64{1, 2, 3},
+				Deadline: tc.args,
+			}))
+			response, err := network.Receive[protocol.Response](ctx, clientConn)
+			require.NoError(t, err)
+
+			assert.NoError(t, <-errChan)
+			assert.Equal(t, tc.wantType, response.Type)
+			if tc.wantType == protocol.MessageTypeError {
+				assert.Equal(t, protocol.ErrorDeadlineExceeded, response.Error)
+
+				return
+			}
+			assert.Equal(t, int64(6), response.Payload)
+		})
+	}
+}
+
+func Test_serv_emptyPayload(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer func() {
+		_ = clientConn.Close()
+	}()
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- serv(serverConn)
+	}()
+
+	ctx := context.Background()
+	require.NoError(t, network.Send(ctx, clientConn, protocol.Request{
+		Message: protocol.Message{
+			Type: protocol.MessageTypeRequest,
+		},
+	}))
+	response, err := network.Receive[protocol.Response](ctx, clientConn)
+	require.NoError(t, err)
+
+	assert.NoError(t, <-errChan)
+	assert.Equal(t, protocol.MessageTypeResponse, response.Type)
+	assert.Equal(t, int64(0), response.Payload)
+}
+
+type connStub struct {
+	net.Conn
+	rw io.ReadWriter
+}
+
+func (c *connStub) Read(b []byte) (int, error) {
+	return c.rw.Read(b)
+}
+
+func (c *connStub) Write(b []byte) (int, error) {
+	return c.rw.Write(b)
+}
+
+func (*connStub) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 56324}
+}
+
 type listenerStub struct {
 	net.Listener
+	closed bool
 }
 
 func (*listenerStub) Accept() (net.Conn, error) {
 	return &net.TCPConn{}, nil
 }
 
-func (*listenerStub) Close() error {
+func (l *listenerStub) Close() error {
+	l.closed = true
+
 	return nil
 }
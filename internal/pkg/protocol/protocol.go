@@ -1,10 +1,21 @@
 package protocol
 
+import "time"
+
+// SchemaVersion is version of messages layout, it is increased on incompatible changes
+const SchemaVersion = 1
+
 type MessageType string
 
 const (
 	MessageTypeRequest  MessageType = "request"
 	MessageTypeResponse MessageType = "response"
+	MessageTypeError    MessageType = "error"
+)
+
+// Error codes of error response
+const (
+	ErrorDeadlineExceeded = "deadline_exceeded"
 )
 
 type Message struct {
@@ -14,9 +25,13 @@ type Message struct {
 type Request struct {
 	Message
 	Payload []int64
+	// Deadline after which client abandons request, zero means no deadline.
+	// Client and server clocks are expected to be synchronized
+	Deadline time.Time
 }
 
 type Response struct {
 	Message
 	Payload int64
+	Error   string
 }
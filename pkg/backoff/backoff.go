@@ -0,0 +1,76 @@
+// Package backoff implements exponential backoff with jitter
+package backoff
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Policy struct {
+	Base time.Duration
+	Max  time.Duration
+	// Jitter is a fraction of delay which is randomly subtracted, from 0 to 1
+	Jitter float64
+	// MaxAttempts includes the first attempt, zero means a single attempt
+	MaxAttempts int
+}
+
+// Delay returns wait time after given failed attempt, attempts start from 1
+func (p Policy) Delay(attempt int) time.Duration {
+	if attempt < 1 || p.Base <= 0 {
+		return 0
+	}
+
+	limit := p.Max
+	if limit <= 0 {
+		limit = math.MaxInt64 / 2
+	}
+
+	delay := p.Base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+
+	if p.Jitter > 0 {
+		delay -= time.Duration(p.Jitter * rand.Float64() * float64(delay))
+	}
+
+	return delay
+}
+
+// Retry runs function until success, attempts exhaustion or context cancellation
+func (p Policy) Retry(ctx context.Context, runFunc func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := runFunc()
+		if err == nil {
+			return nil
+		}
+		if attempt >= p.MaxAttempts {
+			return errors.Wrapf(err, "attempt %d", attempt)
+		}
+
+		if err := Wait(ctx, p.Delay(attempt)); err != nil {
+			return err
+		}
+	}
+}
+
+// Wait sleeps for delay or until context is done
+func Wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
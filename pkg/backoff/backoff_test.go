@@ -0,0 +1,134 @@
+package backoff
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy_Delay(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func() (Policy, int)
+		wantResult time.Duration
+	}{
+		{
+			name: "First attempt",
+			args: func() (Policy, int) {
+				return Policy{Base: time.Millisecond, Max: time.Second}, 1
+			},
+			wantResult: time.Millisecond,
+		},
+		{
+			name: "Exponential growth",
+			args: func() (Policy, int) {
+				return Policy{Base: time.Millisecond, Max: time.Second}, 4
+			},
+			wantResult: 8 * time.Millisecond,
+		},
+		{
+			name: "Max limit",
+			args: func() (Policy, int) {
+				return Policy{Base: time.Millisecond, Max: time.Second}, 100
+			},
+			wantResult: time.Second,
+		},
+		{
+			name: "Zero base",
+			args: func() (Policy, int) {
+				return Policy{}, 3
+			},
+			wantResult: 0,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			p, attempt := tc.args()
+
+			assert.Equal(t, tc.wantResult, p.Delay(attempt))
+		})
+	}
+
+	t.Run("Jitter", func(t *testing.T) {
+		p := Policy{Base: time.Second, Max: time.Second, Jitter: 0.5}
+		for i := 0; i < 100; i++ {
+			result := p.Delay(1)
+
+			assert.GreaterOrEqual(t, result, 500*time.Millisecond)
+			assert.LessOrEqual(t, result, time.Second)
+		}
+	})
+}
+
+func TestPolicy_Retry(t *testing.T) {
+	testCaseList := []struct {
+		name          string
+		args          func() (context.Context, Policy, int)
+		wantCallCount int
+		wantError     bool
+	}{
+		{
+			name: "Success after retries",
+			args: func() (context.Context, Policy, int) {
+				return context.Background(), Policy{Base: time.Millisecond, MaxAttempts: 5}, 3
+			},
+			wantCallCount: 3,
+		},
+		{
+			name: "Attempts exhausted",
+			args: func() (context.Context, Policy, int) {
+				return context.Background(), Policy{Base: time.Millisecond, MaxAttempts: 2}, 10
+			},
+			wantCallCount: 2,
+			wantError:     true,
+		},
+		{
+			name: "Single attempt by default",
+			args: func() (context.Context, Policy, int) {
+				return context.Background(), Policy{}, 10
+			},
+			wantCallCount: 1,
+			wantError:     true,
+		},
+		{
+			name: "Context canceled",
+			args: func() (context.Context, Policy, int) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				return ctx, Policy{Base: time.Hour, MaxAttempts: 5}, 10
+			},
+			wantCallCount: 1,
+			wantError:     true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, p, failCount := tc.args()
+
+			callCount := 0
+			err := p.Retry(ctx, func() error {
+				callCount++
+				if callCount < failCount {
+					return errors.New("example error")
+				}
+
+				return nil
+			})
+
+			assert.Equal(t, tc.wantCallCount, callCount)
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,198 @@
+// Package breaker implements circuit breaker over a sliding window of outcomes
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int32
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type Policy struct {
+	// WindowSize is a number of recent outcomes used for failure ratio, zero disables ratio check
+	WindowSize   int
+	FailureRatio float64
+	// MinRequests is a number of outcomes in window required for ratio check
+	MinRequests int
+	// ConsecutiveFailures opens circuit regardless of ratio, zero disables the check
+	ConsecutiveFailures int
+	// OpenTimeout is a time before open circuit lets probe requests through
+	OpenTimeout time.Duration
+	// HalfOpenRequests is a number of successful probes required to close circuit
+	HalfOpenRequests int
+}
+
+func New(policy Policy, onStateChange func(from, to State)) *Breaker {
+	if policy.HalfOpenRequests < 1 {
+		policy.HalfOpenRequests = 1
+	}
+	if policy.MinRequests < 1 {
+		policy.MinRequests = 1
+	}
+
+	return &Breaker{
+		policy:        policy,
+		onStateChange: onStateChange,
+		window:        make([]bool, policy.WindowSize),
+		now:           time.Now,
+	}
+}
+
+type Breaker struct {
+	policy        Policy
+	onStateChange func(from, to State)
+	now           func() time.Time
+
+	mu                    sync.Mutex
+	state                 State
+	openedAt              time.Time
+	window                []bool
+	windowPos             int
+	windowCnt             int
+	failureCnt            int
+	consecutiveFailureCnt int
+	probeCnt              int
+	probeSuccessCnt       int
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Allow returns ErrOpen when request must fail fast, otherwise Done must be called with request result
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	from := b.state
+
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.policy.OpenTimeout {
+		b.setState(StateHalfOpen)
+	}
+
+	var err error
+	switch b.state {
+	case StateOpen:
+		err = ErrOpen
+	case StateHalfOpen:
+		if b.probeCnt >= b.policy.HalfOpenRequests {
+			err = ErrOpen
+
+			break
+		}
+		b.probeCnt++
+	}
+
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+
+	return err
+}
+
+// Done records request result
+func (b *Breaker) Done(err error) {
+	b.mu.Lock()
+	from := b.state
+
+	switch b.state {
+	case StateHalfOpen:
+		if err != nil {
+			b.setState(StateOpen)
+
+			break
+		}
+		b.probeSuccessCnt++
+		if b.probeSuccessCnt >= b.policy.HalfOpenRequests {
+			b.setState(StateClosed)
+		}
+	case StateClosed:
+		b.record(err != nil)
+		if b.tripped() {
+			b.setState(StateOpen)
+		}
+	}
+
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+func (b *Breaker) record(failed bool) {
+	if failed {
+		b.consecutiveFailureCnt++
+	} else {
+		b.consecutiveFailureCnt = 0
+	}
+
+	if len(b.window) == 0 {
+		return
+	}
+	if b.windowCnt == len(b.window) {
+		if b.window[b.windowPos] {
+			b.failureCnt--
+		}
+	} else {
+		b.windowCnt++
+	}
+	b.window[b.windowPos] = failed
+	if failed {
+		b.failureCnt++
+	}
+	b.windowPos = (b.windowPos + 1) % len(b.window)
+}
+
+func (b *Breaker) tripped() bool {
+	if b.policy.ConsecutiveFailures > 0 && b.consecutiveFailureCnt >= b.policy.ConsecutiveFailures {
+		return true
+	}
+
+	return len(b.window) > 0 &&
+		b.policy.FailureRatio > 0 &&
+		b.windowCnt >= b.policy.MinRequests &&
+		float64(b.failureCnt)/float64(b.windowCnt) >= b.policy.FailureRatio
+}
+
+func (b *Breaker) setState(state State) {
+	b.state = state
+	b.probeCnt, b.probeSuccessCnt = 0, 0
+
+	switch state {
+	case StateOpen:
+		b.openedAt = b.now()
+	case StateClosed:
+		b.windowPos, b.windowCnt, b.failureCnt, b.consecutiveFailureCnt = 0, 0, 0, 0
+	}
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from != to && b.onStateChange != nil {
+		b.onStateChange(from, to)
+	}
+}
@@ -0,0 +1,157 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errExample = errors.New("example error")
+
+func TestBreaker(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      func(b *Breaker, clock *time.Time)
+		policy    Policy
+		wantState State
+	}{
+		{
+			name:   "Disabled",
+			policy: Policy{},
+			args: func(b *Breaker, _ *time.Time) {
+				for i := 0; i < 100; i++ {
+					require.NoError(t, b.Allow())
+					b.Done(errExample)
+				}
+			},
+			wantState: StateClosed,
+		},
+		{
+			name:   "Consecutive failures",
+			policy: Policy{ConsecutiveFailures: 3, OpenTimeout: time.Second},
+			args: func(b *Breaker, _ *time.Time) {
+				for i := 0; i < 3; i++ {
+					require.NoError(t, b.Allow())
+					b.Done(errExample)
+				}
+				assert.ErrorIs(t, b.Allow(), ErrOpen)
+			},
+			wantState: StateOpen,
+		},
+		{
+			name:   "Consecutive failures reset by success",
+			policy: Policy{ConsecutiveFailures: 3, OpenTimeout: time.Second},
+			args: func(b *Breaker, _ *time.Time) {
+				for i := 0; i < 10; i++ {
+					require.NoError(t, b.Allow())
+					if i%2 == 0 {
+						b.Done(errExample)
+					} else {
+						b.Done(nil)
+					}
+				}
+			},
+			wantState: StateClosed,
+		},
+		{
+			name:   "Failure ratio",
+			policy: Policy{WindowSize: 4, FailureRatio: 0.5, MinRequests: 4, OpenTimeout: time.Second},
+			args: func(b *Breaker, _ *time.Time) {
+				for _, err := range []error{nil, errExample, nil, errExample} {
+					require.NoError(t, b.Allow())
+					b.Done(err)
+				}
+			},
+			wantState: StateOpen,
+		},
+		{
+			name:   "Failure ratio below min requests",
+			policy: Policy{WindowSize: 4, FailureRatio: 0.5, MinRequests: 4, OpenTimeout: time.Second},
+			args: func(b *Breaker, _ *time.Time) {
+				for _, err := range []error{errExample, errExample, errExample} {
+					require.NoError(t, b.Allow())
+					b.Done(err)
+				}
+			},
+			wantState: StateClosed,
+		},
+		{
+			name:   "Sliding window forgets old failures",
+			policy: Policy{WindowSize: 4, FailureRatio: 0.5, MinRequests: 4, OpenTimeout: time.Second},
+			args: func(b *Breaker, _ *time.Time) {
+				for _, err := range []error{errExample, nil, nil, nil, nil, errExample} {
+					require.NoError(t, b.Allow())
+					b.Done(err)
+				}
+			},
+			wantState: StateClosed,
+		},
+		{
+			name:   "Half-open after timeout",
+			policy: Policy{ConsecutiveFailures: 1, OpenTimeout: time.Second, HalfOpenRequests: 1},
+			args: func(b *Breaker, clock *time.Time) {
+				require.NoError(t, b.Allow())
+				b.Done(errExample)
+				*clock = clock.Add(time.Second)
+
+				require.NoError(t, b.Allow())
+				assert.ErrorIs(t, b.Allow(), ErrOpen)
+			},
+			wantState: StateHalfOpen,
+		},
+		{
+			name:   "Half-open success closes",
+			policy: Policy{ConsecutiveFailures: 1, OpenTimeout: time.Second, HalfOpenRequests: 2},
+			args: func(b *Breaker, clock *time.Time) {
+				require.NoError(t, b.Allow())
+				b.Done(errExample)
+				*clock = clock.Add(time.Second)
+
+				for i := 0; i < 2; i++ {
+					require.NoError(t, b.Allow())
+					b.Done(nil)
+				}
+			},
+			wantState: StateClosed,
+		},
+		{
+			name:   "Half-open failure opens",
+			policy: Policy{ConsecutiveFailures: 1, OpenTimeout: time.Second},
+			args: func(b *Breaker, clock *time.Time) {
+				require.NoError(t, b.Allow())
+				b.Done(errExample)
+				*clock = clock.Add(time.Second)
+
+				require.NoError(t, b.Allow())
+				b.Done(errExample)
+				assert.ErrorIs(t, b.Allow(), ErrOpen)
+			},
+			wantState: StateOpen,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := time.Now()
+			var transitionList []State
+
+			b := New(tc.policy, func(from, to State) {
+				transitionList = append(transitionList, to)
+			})
+			b.now = func() time.Time {
+				return clock
+			}
+
+			tc.args(b, &clock)
+
+			assert.Equal(t, tc.wantState, b.State())
+			if tc.wantState != StateClosed {
+				require.NotEmpty(t, transitionList)
+				assert.Equal(t, tc.wantState, transitionList[len(transitionList)-1])
+			}
+		})
+	}
+}
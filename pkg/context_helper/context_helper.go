@@ -26,3 +26,21 @@ func RunWithTimeout(timeout time.Duration, runFunc func() error) error {
 		return err
 	}
 }
+
+// RunWithContext runs function until it returns or context is done
+func RunWithContext(ctx context.Context, runFunc func() error) error {
+	ctx, span := tracer.Start(ctx, "pkg.context_helper.RunWithContext")
+	defer span.End()
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- runFunc()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case err := <-errChan:
+		return err
+	}
+}
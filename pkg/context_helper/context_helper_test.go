@@ -1,6 +1,7 @@
 package context_helper
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -70,3 +71,58 @@ func TestRunWithTimeout(t *testing.T) {
 		})
 	}
 }
+
+func TestRunWithContext(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      func() (context.Context, func() error)
+		wantError error
+	}{
+		{
+			name: "Success",
+			args: func() (context.Context, func() error) {
+				return context.Background(), func() error {
+					return nil
+				}
+			},
+		},
+		{
+			name: "Error in func",
+			args: func() (context.Context, func() error) {
+				return context.Background(), func() error {
+					return errExample
+				}
+			},
+			wantError: errExample,
+		},
+		{
+			name: "Context canceled",
+			args: func() (context.Context, func() error) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				return ctx, func() error {
+					time.Sleep(time.Second)
+
+					return nil
+				}
+			},
+			wantError: context.Canceled,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			err := RunWithContext(tc.args())
+			if tc.wantError != nil {
+				assert.ErrorIs(t, err, tc.wantError)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+var errExample = errors.New("example error")
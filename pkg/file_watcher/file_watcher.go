@@ -0,0 +1,102 @@
+// Package file_watcher implements polling based file change detection
+package file_watcher
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+func New(path string, interval time.Duration, onChange func([]byte) error) *Watcher {
+	return &Watcher{
+		path:     path,
+		interval: interval,
+		onChange: onChange,
+		stopChan: make(chan struct{}),
+		logger:   logger.New("file_watcher"),
+	}
+}
+
+type Watcher struct {
+	path     string
+	interval time.Duration
+	onChange func([]byte) error
+	stopChan chan struct{}
+	logger   logger.Logger
+
+	modTime time.Time
+	size    int64
+}
+
+// Start loads file content synchronously and then polls it for changes
+func (w *Watcher) Start(ctx context.Context) error {
+	_, span := tracer.Start(ctx, "pkg.file_watcher.Watcher.Start")
+	defer span.End()
+
+	if _, err := w.reload(); err != nil {
+		return errors.Wrapf(err, "initial loading (%s)", w.path)
+	}
+
+	go w.processor(ctx)
+
+	return nil
+}
+
+func (w *Watcher) processor(ctx context.Context) {
+	_, span := tracer.Start(ctx, "pkg.file_watcher.Watcher.processor")
+	defer span.End()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopChan:
+			return
+		case <-ticker.C:
+			reloaded, err := w.reload()
+			if err != nil {
+				w.logger.Error(err, "file reloading", w.path)
+
+				continue
+			}
+			if reloaded {
+				w.logger.Info("file reloaded", w.path)
+			}
+		}
+	}
+}
+
+func (w *Watcher) reload() (bool, error) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false, errors.Wrap(err, "reading file info")
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return false, errors.Wrap(err, "reading file")
+	}
+	if err := w.onChange(data); err != nil {
+		return false, errors.Wrap(err, "applying file content")
+	}
+
+	w.modTime, w.size = info.ModTime(), info.Size()
+
+	return true, nil
+}
+
+func (w *Watcher) Stop(ctx context.Context) {
+	_, span := tracer.Start(ctx, "pkg.file_watcher.Watcher.Stop")
+	defer span.End()
+
+	close(w.stopChan)
+}
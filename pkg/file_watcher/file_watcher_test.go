@@ -0,0 +1,99 @@
+package file_watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher_Start(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      func(dir string) (string, func([]byte) error)
+		wantError bool
+	}{
+		{
+			name: "Success",
+			args: func(dir string) (string, func([]byte) error) {
+				path := filepath.Join(dir, "example.txt")
+				require.NoError(t, os.WriteFile(path, []byte("example"), 0o600))
+
+				return path, func([]byte) error { return nil }
+			},
+			wantError: false,
+		},
+		{
+			name: "Missing file",
+			args: func(dir string) (string, func([]byte) error) {
+				return filepath.Join(dir, "missing.txt"), func([]byte) error { return nil }
+			},
+			wantError: true,
+		},
+		{
+			name: "Change handler error",
+			args: func(dir string) (string, func([]byte) error) {
+				path := filepath.Join(dir, "example.txt")
+				require.NoError(t, os.WriteFile(path, []byte("example"), 0o600))
+
+				return path, func([]byte) error { return errors.New("example error") }
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			path, onChange := tc.args(t.TempDir())
+			w := New(path, time.Hour, onChange)
+
+			err := w.Start(ctx)
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+			defer w.Stop(ctx)
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestWatcher_processor(t *testing.T) {
+	t.Run("Reload on change", func(t *testing.T) {
+		ctx := context.Background()
+		path := filepath.Join(t.TempDir(), "example.txt")
+		require.NoError(t, os.WriteFile(path, []byte("one"), 0o600))
+
+		var (
+			mu     sync.Mutex
+			result []string
+		)
+		w := New(path, time.Millisecond, func(data []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			result = append(result, string(data))
+
+			return nil
+		})
+		require.NoError(t, w.Start(ctx))
+		defer w.Stop(ctx)
+
+		require.NoError(t, os.WriteFile(path, []byte("two!"), 0o600))
+
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return len(result) == 2 && result[1] == "two!"
+		}, time.Second, time.Millisecond)
+	})
+}
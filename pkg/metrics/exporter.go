@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/kirill-a-belov/test_task_framework/pkg/logger"
+	"github.com/kirill-a-belov/test_task_framework/pkg/tracer"
+)
+
+// NewExporter returns exporter logging snapshot of all registered metrics every interval, zero interval disables it
+func NewExporter(interval time.Duration) *Exporter {
+	return &Exporter{
+		interval: interval,
+		stopChan: make(chan struct{}),
+		logger:   logger.New("metrics"),
+	}
+}
+
+type Exporter struct {
+	interval time.Duration
+	stopChan chan struct{}
+	logger   logger.Logger
+}
+
+func (e *Exporter) Start(ctx context.Context) {
+	_, span := tracer.Start(ctx, "pkg.metrics.Exporter.Start")
+	defer span.End()
+
+	if e.interval <= 0 {
+		return
+	}
+
+	go e.processor(ctx)
+}
+
+func (e *Exporter) processor(ctx context.Context) {
+	_, span := tracer.Start(ctx, "pkg.metrics.Exporter.processor")
+	defer span.End()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+		case <-ticker.C:
+			e.export()
+		}
+	}
+}
+
+// Stop stops periodic export and exports final values, e.g. counters of short runs
+func (e *Exporter) Stop(ctx context.Context) {
+	_, span := tracer.Start(ctx, "pkg.metrics.Exporter.Stop")
+	defer span.End()
+
+	if e.interval <= 0 {
+		return
+	}
+
+	close(e.stopChan)
+	e.export()
+}
+
+// export logs all metrics in one line as name and value pairs
+func (e *Exporter) export() {
+	sampleList := Snapshot()
+	args := make([]interface{}, 0, 2*len(sampleList)+1)
+	args = append(args, "metrics")
+	for _, s := range sampleList {
+		args = append(args, s.Name, s.Value)
+	}
+
+	e.logger.Info(args...)
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// loggerStub records arguments of Info calls
+type loggerStub struct {
+	mu       sync.Mutex
+	infoList [][]interface{}
+}
+
+func (l *loggerStub) Error(error, ...interface{}) {}
+
+func (l *loggerStub) Info(args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.infoList = append(l.infoList, args)
+}
+
+func (l *loggerStub) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.infoList)
+}
+
+func (l *loggerStub) last() []interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.infoList[len(l.infoList)-1]
+}
+
+func TestExporter(t *testing.T) {
+	testCaseList := []struct {
+		name     string
+		interval time.Duration
+		wantLog  bool
+	}{
+		{
+			name:     "Periodic export",
+			interval: 10 * time.Millisecond,
+			wantLog:  true,
+		},
+		{
+			name: "Disabled",
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			NewCounter("example.exporter").Inc()
+
+			ctx := context.Background()
+			stub := &loggerStub{}
+			e := NewExporter(tc.interval)
+			e.logger = stub
+			e.Start(ctx)
+			if tc.wantLog {
+				assert.Eventually(t, func() bool {
+					return stub.count() > 0
+				}, time.Second, 10*time.Millisecond)
+			}
+			e.Stop(ctx)
+
+			if !tc.wantLog {
+				assert.Zero(t, stub.count())
+
+				return
+			}
+			// final export on stop
+			last := stub.last()
+			assert.Equal(t, "metrics", last[0])
+			assert.Contains(t, last, "example.exporter")
+		})
+	}
+}
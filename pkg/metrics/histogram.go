@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	histogramSubBucketBits  = 6
+	histogramSubBucketCnt   = 1 << histogramSubBucketBits
+	histogramSubBucketHalf  = histogramSubBucketCnt / 2
+	histogramBucketCnt      = histogramSubBucketCnt + (64-histogramSubBucketBits)*histogramSubBucketHalf
+	histogramQuantileFactor = 1_000_000
+)
+
+// Histogram is a lock free log-linear latency histogram with about 3% precision
+type Histogram struct {
+	bucketList [histogramBucketCnt]atomic.Int64
+	count      atomic.Int64
+	sum        atomic.Int64
+	min        atomic.Int64
+	max        atomic.Int64
+}
+
+func NewHistogram() *Histogram {
+	h := &Histogram{}
+	h.min.Store(math.MaxInt64)
+
+	return h
+}
+
+func (h *Histogram) Observe(d time.Duration) {
+	v := int64(d)
+	if v < 0 {
+		v = 0
+	}
+
+	h.bucketList[bucketIndex(v)].Add(1)
+	h.count.Add(1)
+	h.sum.Add(v)
+	for current := h.min.Load(); v < current; current = h.min.Load() {
+		if h.min.CompareAndSwap(current, v) {
+			break
+		}
+	}
+	for current := h.max.Load(); v > current; current = h.max.Load() {
+		if h.max.CompareAndSwap(current, v) {
+			break
+		}
+	}
+}
+
+func (h *Histogram) Count() int64 {
+	return h.count.Load()
+}
+
+func (h *Histogram) Min() time.Duration {
+	if h.Count() == 0 {
+		return 0
+	}
+
+	return time.Duration(h.min.Load())
+}
+
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max.Load())
+}
+
+func (h *Histogram) Mean() time.Duration {
+	count := h.Count()
+	if count == 0 {
+		return 0
+	}
+
+	return time.Duration(h.sum.Load() / count)
+}
+
+// Quantile returns value below which q part of observations fall, q is from 0 to 1
+func (h *Histogram) Quantile(q float64) time.Duration {
+	count := h.Count()
+	if count == 0 {
+		return 0
+	}
+
+	rank := (int64(q*histogramQuantileFactor)*count + histogramQuantileFactor - 1) / histogramQuantileFactor
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int64
+	for i := range h.bucketList {
+		seen += h.bucketList[i].Load()
+		if seen >= rank {
+			result := bucketUpperBound(i)
+			if result > h.max.Load() {
+				result = h.max.Load()
+			}
+
+			return time.Duration(result)
+		}
+	}
+
+	return h.Max()
+}
+
+func bucketIndex(v int64) int {
+	if v < histogramSubBucketCnt {
+		return int(v)
+	}
+
+	shift := bits.Len64(uint64(v)) - histogramSubBucketBits
+
+	return histogramSubBucketCnt + (shift-1)*histogramSubBucketHalf + int(v>>shift) - histogramSubBucketHalf
+}
+
+func bucketUpperBound(i int) int64 {
+	if i < histogramSubBucketCnt {
+		return int64(i)
+	}
+
+	shift := (i-histogramSubBucketCnt)/histogramSubBucketHalf + 1
+	mantissa := int64((i-histogramSubBucketCnt)%histogramSubBucketHalf + histogramSubBucketHalf)
+
+	return (mantissa+1)<<shift - 1
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram_Quantile(t *testing.T) {
+	testCaseList := []struct {
+		name       string
+		args       func() (*Histogram, float64)
+		wantResult time.Duration
+	}{
+		{
+			name: "Empty",
+			args: func() (*Histogram, float64) {
+				return NewHistogram(), 0.5
+			},
+			wantResult: 0,
+		},
+		{
+			name: "Small exact values",
+			args: func() (*Histogram, float64) {
+				h := NewHistogram()
+				for i := 1; i <= 10; i++ {
+					h.Observe(time.Duration(i))
+				}
+
+				return h, 0.9
+			},
+			wantResult: 9,
+		},
+		{
+			name: "Max limit",
+			args: func() (*Histogram, float64) {
+				h := NewHistogram()
+				h.Observe(time.Second)
+
+				return h, 0.999
+			},
+			wantResult: time.Second,
+		},
+	}
+
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			h, q := tc.args()
+
+			assert.Equal(t, tc.wantResult, h.Quantile(q))
+		})
+	}
+
+	t.Run("Precision", func(t *testing.T) {
+		h := NewHistogram()
+		for i := 1; i <= 1000; i++ {
+			h.Observe(time.Duration(i) * time.Millisecond)
+		}
+
+		for _, q := range []float64{0.5, 0.9, 0.99, 0.999} {
+			want := float64(time.Duration(q*1000) * time.Millisecond)
+			assert.InDelta(t, want, float64(h.Quantile(q)), want*0.04, "quantile %v", q)
+		}
+		assert.Equal(t, time.Millisecond, h.Min())
+		assert.Equal(t, time.Second, h.Max())
+		assert.Equal(t, int64(1000), h.Count())
+	})
+}
+
+func Test_bucketIndex(t *testing.T) {
+	t.Run("Value within bucket bounds", func(t *testing.T) {
+		for _, v := range []int64{0, 63, 64, 65, 1000, 123456789, math.MaxInt64} {
+			i := bucketIndex(v)
+
+			assert.Less(t, i, histogramBucketCnt)
+			assert.LessOrEqual(t, v, bucketUpperBound(i))
+			if i > 0 {
+				assert.Greater(t, v, bucketUpperBound(i-1))
+			}
+		}
+	})
+}
@@ -0,0 +1,101 @@
+// Package metrics implements in-process metrics registry
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+type Counter struct {
+	value atomic.Int64
+}
+
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+func (c *Counter) Add(delta int64) {
+	c.value.Add(delta)
+}
+
+func (c *Counter) Value() int64 {
+	return c.value.Load()
+}
+
+type Gauge struct {
+	value atomic.Int64
+}
+
+func (g *Gauge) Set(value int64) {
+	g.value.Store(value)
+}
+
+func (g *Gauge) Add(delta int64) {
+	g.value.Add(delta)
+}
+
+func (g *Gauge) Value() int64 {
+	return g.value.Load()
+}
+
+var registry = struct {
+	sync.Mutex
+	counterList map[string]*Counter
+	gaugeList   map[string]*Gauge
+}{
+	counterList: make(map[string]*Counter),
+	gaugeList:   make(map[string]*Gauge),
+}
+
+// NewCounter returns registered counter or registers a new one
+func NewCounter(name string) *Counter {
+	registry.Lock()
+	defer registry.Unlock()
+
+	c, ok := registry.counterList[name]
+	if !ok {
+		c = &Counter{}
+		registry.counterList[name] = c
+	}
+
+	return c
+}
+
+// NewGauge returns registered gauge or registers a new one
+func NewGauge(name string) *Gauge {
+	registry.Lock()
+	defer registry.Unlock()
+
+	g, ok := registry.gaugeList[name]
+	if !ok {
+		g = &Gauge{}
+		registry.gaugeList[name] = g
+	}
+
+	return g
+}
+
+type Sample struct {
+	Name  string
+	Value int64
+}
+
+// Snapshot returns current values of all registered metrics sorted by name
+func Snapshot() []Sample {
+	registry.Lock()
+	defer registry.Unlock()
+
+	result := make([]Sample, 0, len(registry.counterList)+len(registry.gaugeList))
+	for name, c := range registry.counterList {
+		result = append(result, Sample{Name: name, Value: c.Value()})
+	}
+	for name, g := range registry.gaugeList {
+		result = append(result, Sample{Name: name, Value: g.Value()})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCounter(t *testing.T) {
+	t.Run("Same name same counter", func(t *testing.T) {
+		// registry is global, so only increments of this run are checked
+		c := NewCounter("example.counter")
+		before := c.Value()
+		c.Inc()
+		c.Add(2)
+
+		assert.Equal(t, before+3, NewCounter("example.counter").Value())
+	})
+}
+
+func TestNewGauge(t *testing.T) {
+	t.Run("Same name same gauge", func(t *testing.T) {
+		g := NewGauge("example.gauge")
+		g.Set(5)
+		g.Add(-1)
+
+		assert.Equal(t, int64(4), NewGauge("example.gauge").Value())
+	})
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Run("Sorted by name", func(t *testing.T) {
+		c := NewCounter("example.snapshot.b")
+		c.Inc()
+		NewGauge("example.snapshot.a").Set(2)
+
+		var result []Sample
+		for _, s := range Snapshot() {
+			if s.Name == "example.snapshot.a" || s.Name == "example.snapshot.b" {
+				result = append(result, s)
+			}
+		}
+
+		assert.Equal(t, []Sample{
+			{Name: "example.snapshot.a", Value: 2},
+			{Name: "example.snapshot.b", Value: c.Value()},
+		}, result)
+	})
+}
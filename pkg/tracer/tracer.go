@@ -1,17 +1,106 @@
 package tracer
 
-import "context"
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+)
 
-type exampleSpan struct{}
+const traceParentVersion = "00"
+
+type spanContextKey struct{}
+
+// SpanContext identifies span and trace it belongs to
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// IsValid returns true for span context with both ids set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceParent formats span context as W3C trace context traceparent value
+func (sc SpanContext) TraceParent() string {
+	return fmt.Sprintf("%s-%s-%s-01",
+		traceParentVersion,
+		hex.EncodeToString(sc.TraceID[:]),
+		hex.EncodeToString(sc.SpanID[:]),
+	)
+}
+
+// ParseTraceParent parses W3C trace context traceparent value
+func ParseTraceParent(value string) (SpanContext, error) {
+	partList := strings.Split(value, "-")
+	if len(partList) != 4 || partList[0] != traceParentVersion {
+		return SpanContext{}, fmt.Errorf("invalid traceparent (%s)", value)
+	}
+
+	sc := SpanContext{}
+	if n, err := hex.Decode(sc.TraceID[:], []byte(partList[1])); err != nil || n != len(sc.TraceID) {
+		return SpanContext{}, fmt.Errorf("invalid trace id (%s)", partList[1])
+	}
+	if n, err := hex.Decode(sc.SpanID[:], []byte(partList[2])); err != nil || n != len(sc.SpanID) {
+		return SpanContext{}, fmt.Errorf("invalid span id (%s)", partList[2])
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("invalid traceparent (%s)", value)
+	}
+
+	return sc, nil
+}
+
+// ContextWithSpanContext returns context continuing span, e.g. received from another service
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns span context of current span, zero value when there is no span
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+
+	return sc
+}
+
+type exampleSpan struct {
+	spanContext SpanContext
+}
 
 func (span *exampleSpan) End() {}
 
+func (span *exampleSpan) SpanContext() SpanContext {
+	return span.spanContext
+}
+
 type Span interface {
 	End()
+	SpanContext() SpanContext
 }
 
+// Start starts span as a child of context span, new trace is started when there is no one
 func Start(ctx context.Context, name string) (context.Context, Span) {
 	_ = name
 
-	return ctx, &exampleSpan{}
+	sc := SpanContext{TraceID: SpanContextFromContext(ctx).TraceID}
+	if sc.TraceID == [16]byte{} {
+		binary.BigEndian.PutUint64(sc.TraceID[:8], randomID())
+		binary.BigEndian.PutUint64(sc.TraceID[8:], randomID())
+	}
+	binary.BigEndian.PutUint64(sc.SpanID[:], randomID())
+
+	return ContextWithSpanContext(ctx, sc), &exampleSpan{spanContext: sc}
+}
+
+// randomID returns non-zero id, ids need uniqueness only, so process seeded generator is used
+// instead of syscall backed crypto one on this per call path
+func randomID() uint64 {
+	for {
+		if id := rand.Uint64(); id != 0 {
+			return id
+		}
+	}
 }
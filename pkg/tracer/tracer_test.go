@@ -10,28 +10,81 @@ import (
 
 func TestStart(t *testing.T) {
 	testCaseList := []struct {
-		name string
-		args func() (input context.Context, wantResult context.Context)
+		name          string
+		args          func() context.Context
+		wantSameTrace bool
 	}{
 		{
-			name: "Success",
-			args: func() (input context.Context, wantResult context.Context) {
-				input = context.Background()
-				wantResult = input
+			name: "New trace",
+			args: func() context.Context {
+				return context.Background()
+			},
+		},
+		{
+			name: "Child span",
+			args: func() context.Context {
+				ctx, _ := Start(context.Background(), "parent")
 
-				return
+				return ctx
 			},
+			wantSameTrace: true,
 		},
 	}
 	for _, tc := range testCaseList {
 		t.Run(tc.name, func(t *testing.T) {
-			input, wantResult := tc.args()
+			input := tc.args()
 
 			resultCTX, resultSpan := Start(input, "example")
 			require.NotPanics(t, func() {
 				resultSpan.End()
 			})
-			assert.Equal(t, wantResult, resultCTX)
+
+			parent, result := SpanContextFromContext(input), SpanContextFromContext(resultCTX)
+			assert.True(t, result.IsValid())
+			assert.Equal(t, resultSpan.SpanContext(), result)
+			assert.NotEqual(t, parent.SpanID, result.SpanID)
+			assert.Equal(t, tc.wantSameTrace, parent.TraceID == result.TraceID)
+		})
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	testCaseList := []struct {
+		name      string
+		args      string
+		wantError bool
+	}{
+		{
+			name: "Success",
+			args: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		{
+			name:      "Invalid version",
+			args:      "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantError: true,
+		},
+		{
+			name:      "Invalid trace id",
+			args:      "00-4bf92f3577b34da6-00f067aa0ba902b7-01",
+			wantError: true,
+		},
+		{
+			name:      "Zero span id",
+			args:      "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+			wantError: true,
+		},
+	}
+	for _, tc := range testCaseList {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := ParseTraceParent(tc.args)
+			if tc.wantError {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.args, result.TraceParent())
 		})
 	}
 }